@@ -73,7 +73,7 @@ require (
 require (
 	cloud.google.com/go v0.123.0 // indirect
 	cloud.google.com/go/auth v0.17.0 // indirect
-	cloud.google.com/go/compute/metadata v0.9.0 // indirect
+	cloud.google.com/go/compute/metadata v0.9.0
 	cloud.google.com/go/longrunning v0.7.0 // indirect
 	github.com/anthropics/anthropic-sdk-go v1.19.0
 	github.com/glebarez/sqlite v1.8.0
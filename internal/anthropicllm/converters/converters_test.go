@@ -15,14 +15,25 @@
 package converters_test
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/jsonschema-go/jsonschema"
 	"google.golang.org/genai"
 
 	"google.golang.org/adk/internal/anthropicllm/converters"
+	"google.golang.org/adk/model"
 )
 
 func TestContentsToMessages_SimpleText(t *testing.T) {
@@ -140,6 +151,54 @@ func TestSystemInstructionToSystem(t *testing.T) {
 	}
 }
 
+func TestSystemContentsToSystem(t *testing.T) {
+	contents := []*genai.Content{
+		genai.NewContentFromText("You are a travel agent.", "system"),
+		genai.NewContentFromText("Hi", "user"),
+		genai.NewContentFromText("Quote prices in euros.", "SYSTEM"),
+	}
+
+	var got []string
+	for _, block := range converters.SystemContentsToSystem(contents) {
+		got = append(got, block.Text)
+	}
+	if diff := cmp.Diff([]string{"You are a travel agent.", "Quote prices in euros."}, got); diff != "" {
+		t.Errorf("SystemContentsToSystem() mismatch (-want +got):\n%s", diff)
+	}
+
+	messages, err := converters.ContentsToMessages(contents)
+	if err != nil {
+		t.Fatalf("ContentsToMessages() error = %v", err)
+	}
+	if len(messages) != 1 {
+		t.Errorf("ContentsToMessages() returned %d messages, want only the user message", len(messages))
+	}
+}
+
+func TestSystemInstructionToSystem_CacheBreakpoints(t *testing.T) {
+	breakpoint := &genai.Part{InlineData: &genai.Blob{MIMEType: converters.CacheBreakpointMIMEType}}
+	instruction := &genai.Content{
+		Role: "system",
+		Parts: []*genai.Part{
+			breakpoint, // nothing to cache yet, ignored
+			{Text: "Static preamble."},
+			{Text: "Reference material."},
+			breakpoint,
+			{Text: "Dynamic instructions."},
+		},
+	}
+
+	blocks := converters.SystemInstructionToSystem(instruction)
+	if len(blocks) != 3 {
+		t.Fatalf("SystemInstructionToSystem() returned %d blocks, want 3", len(blocks))
+	}
+	for i, wantCached := range []bool{false, true, false} {
+		if cached := blocks[i].CacheControl.Type != ""; cached != wantCached {
+			t.Errorf("block %d (%q) cached = %v, want %v", i, blocks[i].Text, cached, wantCached)
+		}
+	}
+}
+
 func TestStopReasonToFinishReason(t *testing.T) {
 	tests := []struct {
 		name string
@@ -150,6 +209,8 @@ func TestStopReasonToFinishReason(t *testing.T) {
 		{"max_tokens", anthropic.StopReasonMaxTokens, genai.FinishReasonMaxTokens},
 		{"stop_sequence", anthropic.StopReasonStopSequence, genai.FinishReasonStop},
 		{"tool_use", anthropic.StopReasonToolUse, genai.FinishReasonStop},
+		{"pause_turn", anthropic.StopReasonPauseTurn, converters.FinishReasonPauseTurn},
+		{"refusal", anthropic.StopReasonRefusal, genai.FinishReasonSafety},
 		{"unknown", anthropic.StopReason("unknown"), genai.FinishReasonUnspecified},
 	}
 
@@ -164,11 +225,12 @@ func TestStopReasonToFinishReason(t *testing.T) {
 }
 
 func TestUsageToMetadata(t *testing.T) {
-	usage := anthropic.Usage{InputTokens: 10, OutputTokens: 20}
+	usage := anthropic.Usage{InputTokens: 10, OutputTokens: 20, CacheReadInputTokens: 5, CacheCreationInputTokens: 3}
 	want := &genai.GenerateContentResponseUsageMetadata{
-		PromptTokenCount:     10,
-		CandidatesTokenCount: 20,
-		TotalTokenCount:      30,
+		PromptTokenCount:        18,
+		CandidatesTokenCount:    20,
+		TotalTokenCount:         38,
+		CachedContentTokenCount: 5,
 	}
 	got := converters.UsageToMetadata(usage)
 	if diff := cmp.Diff(want, got); diff != "" {
@@ -176,6 +238,199 @@ func TestUsageToMetadata(t *testing.T) {
 	}
 }
 
+func TestMessageToLLMResponse_WebSearchRequests(t *testing.T) {
+	msg := &anthropic.Message{
+		StopReason: anthropic.StopReasonEndTurn,
+		Usage: anthropic.Usage{
+			InputTokens:   10,
+			OutputTokens:  20,
+			ServerToolUse: anthropic.ServerToolUsage{WebSearchRequests: 2},
+		},
+	}
+	resp, err := converters.MessageToLLMResponse(msg)
+	if err != nil {
+		t.Fatalf("MessageToLLMResponse() error = %v", err)
+	}
+	if got := resp.CustomMetadata[converters.MetadataKeyWebSearchRequests]; got != int64(2) {
+		t.Errorf("CustomMetadata[%q] = %v, want 2", converters.MetadataKeyWebSearchRequests, got)
+	}
+
+	msg.Usage.ServerToolUse.WebSearchRequests = 0
+	resp, err = converters.MessageToLLMResponse(msg)
+	if err != nil {
+		t.Fatalf("MessageToLLMResponse() error = %v", err)
+	}
+	if got, ok := resp.CustomMetadata[converters.MetadataKeyWebSearchRequests]; ok {
+		t.Errorf("CustomMetadata[%q] = %v, want unset without web searches", converters.MetadataKeyWebSearchRequests, got)
+	}
+}
+
+func TestCheckServedModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		served    string
+		want      bool
+	}{
+		{name: "same", requested: "claude-sonnet-4-20250514", served: "claude-sonnet-4-20250514"},
+		{name: "alias", requested: "claude-sonnet-4-5", served: "claude-sonnet-4-5-20250929"},
+		{name: "major_alias", requested: "claude-sonnet-4-0", served: "claude-sonnet-4-20250514"},
+		{name: "latest_alias", requested: "claude-3-5-haiku-latest", served: "claude-3-5-haiku-20241022"},
+		{name: "vertex", requested: "claude-sonnet-4@20250514", served: "claude-sonnet-4-20250514"},
+		{name: "not_served", requested: "claude-sonnet-4-20250514"},
+		{name: "other_model", requested: "claude-sonnet-4-20250514", served: "claude-3-5-haiku-20241022", want: true},
+		{name: "other_version", requested: "claude-sonnet-4-5", served: "claude-sonnet-4-20250514", want: true},
+		{name: "other_minor_version", requested: "claude-opus-4-0", served: "claude-opus-4-1-20250805", want: true},
+		{name: "alias_prefix", requested: "claude-sonnet-4", served: "claude-sonnet-4-5-20250929", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &model.LLMResponse{}
+			if tt.served != "" {
+				resp.CustomMetadata = map[string]any{converters.MetadataKeyModel: tt.served}
+			}
+			if got := converters.CheckServedModel(resp, tt.requested); got != tt.want {
+				t.Errorf("CheckServedModel() = %v, want %v", got, tt.want)
+			}
+			got, ok := resp.CustomMetadata[converters.MetadataKeyRequestedModel]
+			if ok != tt.want {
+				t.Fatalf("CustomMetadata[%q] = %v, set = %v, want set = %v", converters.MetadataKeyRequestedModel, got, ok, tt.want)
+			}
+			if ok && got != tt.requested {
+				t.Errorf("CustomMetadata[%q] = %v, want %q", converters.MetadataKeyRequestedModel, got, tt.requested)
+			}
+		})
+	}
+}
+
+func TestThinkingSignatureReplay(t *testing.T) {
+	tests := []struct {
+		name         string
+		signature    string
+		wantThinking bool
+	}{
+		{name: "valid", signature: "c2lnbmF0dXJl", wantThinking: true},
+		{name: "empty", signature: ""},
+		{name: "malformed", signature: "c2lnbmF0dXJl!!"},
+		{name: "truncated", signature: "c2lnbmF0dXJ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg anthropic.Message
+			data := fmt.Sprintf(`{"content":[{"type":"thinking","thinking":"Let me think.","signature":%q}],"usage":{"output_tokens":10}}`, tt.signature)
+			if err := json.Unmarshal([]byte(data), &msg); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := converters.MessageToLLMResponse(&msg)
+			if err != nil {
+				t.Fatalf("MessageToLLMResponse() error = %v", err)
+			}
+			streamed := converters.StreamThinkingSignatureToPartialResponse("Let me think.", tt.signature)
+
+			for _, part := range []*genai.Part{resp.Content.Parts[0], streamed.Content.Parts[0]} {
+				block, err := converters.PartToContentBlock(part)
+				if err != nil {
+					t.Fatalf("PartToContentBlock() error = %v", err)
+				}
+				if !tt.wantThinking {
+					if block.OfText == nil || block.OfText.Text != "Let me think." {
+						t.Errorf("PartToContentBlock() = %+v, want the thought as text", block)
+					}
+					continue
+				}
+				if block.OfThinking == nil || block.OfThinking.Signature != tt.signature {
+					t.Errorf("PartToContentBlock() = %+v, want thinking signed %q", block, tt.signature)
+				}
+			}
+		})
+	}
+}
+
+func TestMessageToLLMResponse_ThinkingTokens(t *testing.T) {
+	tests := []struct {
+		name           string
+		message        string
+		want           int32
+		wantCandidates int32
+	}{
+		{
+			name:           "no_thinking",
+			message:        `{"content":[{"type":"text","text":"Hello"}],"usage":{"output_tokens":10}}`,
+			wantCandidates: 10,
+		},
+		{
+			name:           "thinking",
+			message:        `{"content":[{"type":"thinking","thinking":"Let me think about it.","signature":"c2ln"},{"type":"text","text":"Hello"}],"usage":{"output_tokens":10}}`,
+			want:           6,
+			wantCandidates: 4,
+		},
+		{
+			name:           "characters_not_bytes",
+			message:        `{"content":[{"type":"thinking","thinking":"Réfléchissons-y bien","signature":"c2ln"},{"type":"text","text":"Bonjour"}],"usage":{"output_tokens":10}}`,
+			want:           5,
+			wantCandidates: 5,
+		},
+		{
+			name:    "capped_at_output_tokens",
+			message: `{"content":[{"type":"thinking","thinking":"Let me think about it.","signature":"c2ln"}],"usage":{"output_tokens":3}}`,
+			want:    3,
+		},
+		{
+			name:           "redacted",
+			message:        `{"content":[{"type":"redacted_thinking","data":"c2VjcmV0"},{"type":"text","text":"Hello"}],"usage":{"output_tokens":10}}`,
+			wantCandidates: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg anthropic.Message
+			if err := json.Unmarshal([]byte(tt.message), &msg); err != nil {
+				t.Fatal(err)
+			}
+			resp, err := converters.MessageToLLMResponse(&msg)
+			if err != nil {
+				t.Fatalf("MessageToLLMResponse() error = %v", err)
+			}
+			if got := resp.UsageMetadata.ThoughtsTokenCount; got != tt.want {
+				t.Errorf("ThoughtsTokenCount = %d, want %d", got, tt.want)
+			}
+			if got := resp.UsageMetadata.CandidatesTokenCount; got != tt.wantCandidates {
+				t.Errorf("CandidatesTokenCount = %d, want %d", got, tt.wantCandidates)
+			}
+		})
+	}
+}
+
+func TestMergeTextParts(t *testing.T) {
+	call := &genai.Part{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "lookup"}}
+	thought := &genai.Part{Text: "Hmm.", Thought: true}
+	content := &genai.Content{Role: "model", Parts: []*genai.Part{
+		{Text: "According to the docs, "},
+		{Text: "the answer is 42."},
+		call,
+		{Text: "Checking again."},
+		thought,
+		{Text: "Still 42"},
+		{Text: "."},
+	}}
+
+	converters.MergeTextParts(content)
+
+	want := []*genai.Part{
+		{Text: "According to the docs, the answer is 42."},
+		call,
+		{Text: "Checking again."},
+		thought,
+		{Text: "Still 42."},
+	}
+	if diff := cmp.Diff(want, content.Parts); diff != "" {
+		t.Errorf("MergeTextParts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestToolsToAnthropicTools(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -287,6 +542,48 @@ func TestPartToContentBlock_UnsupportedTypes(t *testing.T) {
 	}
 }
 
+func TestPartToContentBlock_SkippedAndUnsupported(t *testing.T) {
+	tests := []struct {
+		name        string
+		part        *genai.Part
+		unsupported bool
+	}{
+		{name: "nil", part: nil},
+		{name: "blank_text", part: &genai.Part{Text: "  "}},
+		{name: "unsigned_empty_thought", part: &genai.Part{Thought: true}},
+		{name: "inline_video", part: genai.NewPartFromBytes([]byte("video"), "video/mp4"), unsupported: true},
+		{name: "video_uri", part: genai.NewPartFromURI("https://example.com/clip.webm", "video/webm"), unsupported: true},
+		{name: "video_metadata", part: &genai.Part{VideoMetadata: &genai.VideoMetadata{FPS: genai.Ptr(1.0)}}, unsupported: true},
+		{name: "inline_audio", part: genai.NewPartFromBytes([]byte("ID3"), "audio/mpeg"), unsupported: true},
+		{name: "unknown_mime_type", part: genai.NewPartFromBytes([]byte("data"), "application/x-unknown"), unsupported: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := converters.PartToContentBlock(tt.part)
+			if !tt.unsupported {
+				if block != nil || err != nil {
+					t.Errorf("PartToContentBlock() = %v, %v, want the part skipped", block, err)
+				}
+				return
+			}
+			if !errors.Is(err, converters.ErrUnsupportedContent) {
+				t.Errorf("PartToContentBlock() error = %v, want ErrUnsupportedContent", err)
+			}
+		})
+	}
+
+	_, err := converters.PartToContentBlock(genai.NewPartFromBytes([]byte("video"), "video/mp4"))
+	if want := "video input is not supported by Anthropic models (video/mp4)"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("PartToContentBlock(video) error = %v, want %q", err, want)
+	}
+
+	_, err = converters.PartToContentBlock(genai.NewPartFromBytes([]byte("ID3"), "audio/mpeg"))
+	if want := "audio input is not supported by this model (audio/mpeg)"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("PartToContentBlock(audio) error = %v, want %q", err, want)
+	}
+}
+
 func TestFunctionResponseToBlock(t *testing.T) {
 	content := &genai.Content{
 		Role: "user",
@@ -315,6 +612,112 @@ func TestFunctionResponseToBlock(t *testing.T) {
 	}
 }
 
+func TestFunctionResponseToBlock_Parts(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	tests := []struct {
+		name     string
+		response map[string]any
+	}{
+		{
+			name: "parts",
+			response: map[string]any{converters.FunctionResponsePartsKey: []*genai.Part{
+				genai.NewPartFromText("Sales grew 12% in Q3."),
+				genai.NewPartFromBytes(png, "image/png"),
+			}},
+		},
+		{
+			name: "json_round_trip",
+			response: map[string]any{converters.FunctionResponsePartsKey: []any{
+				map[string]any{"text": "Sales grew 12% in Q3."},
+				map[string]any{"inlineData": map[string]any{"data": base64.StdEncoding.EncodeToString(png), "mimeType": "image/png"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &genai.Content{Role: "user", Parts: []*genai.Part{{
+				FunctionResponse: &genai.FunctionResponse{ID: "call_123", Name: "sales_report", Response: tt.response},
+			}}}
+
+			messages, err := converters.ContentsToMessages([]*genai.Content{content})
+			if err != nil {
+				t.Fatalf("ContentsToMessages() error = %v", err)
+			}
+			result := messages[0].Content[0].OfToolResult
+			if result == nil || len(result.Content) != 2 {
+				t.Fatalf("content = %+v, want a tool result with 2 blocks", messages[0].Content[0])
+			}
+			if text := result.Content[0].OfText; text == nil || text.Text != "Sales grew 12% in Q3." {
+				t.Errorf("block 0 = %+v, want the summary text", result.Content[0])
+			}
+			if img := result.Content[1].OfImage; img == nil || img.Source.OfBase64 == nil || img.Source.OfBase64.Data != base64.StdEncoding.EncodeToString(png) {
+				t.Errorf("block 1 = %+v, want the PNG image", result.Content[1])
+			}
+		})
+	}
+}
+
+func TestFunctionResponseToBlock_PartsKeyWithOtherKeys(t *testing.T) {
+	content := &genai.Content{Role: "user", Parts: []*genai.Part{{
+		FunctionResponse: &genai.FunctionResponse{
+			ID:       "call_123",
+			Name:     "list_parts",
+			Response: map[string]any{converters.FunctionResponsePartsKey: []any{"bolt", "nut"}, "total": 2},
+		},
+	}}}
+
+	messages, err := converters.ContentsToMessages([]*genai.Content{content})
+	if err != nil {
+		t.Fatalf("ContentsToMessages() error = %v", err)
+	}
+	result := messages[0].Content[0].OfToolResult
+	if len(result.Content) != 1 || result.Content[0].OfText == nil || result.Content[0].OfText.Text != `{"anthropic:parts":["bolt","nut"],"total":2}` {
+		t.Errorf("tool result content = %+v, want the JSON response", result.Content)
+	}
+}
+
+func TestFunctionResponseToBlock_PartsKeyWithoutParts(t *testing.T) {
+	tests := []struct {
+		name     string
+		response map[string]any
+		want     string
+	}{
+		{
+			name:     "plain_parts_key",
+			response: map[string]any{"parts": []any{map[string]any{"sku": "A1", "qty": 2}}},
+			want:     `{"parts":[{"qty":2,"sku":"A1"}]}`,
+		},
+		{
+			name:     "unknown_fields",
+			response: map[string]any{converters.FunctionResponsePartsKey: []any{map[string]any{"sku": "A1", "qty": 2}}},
+			want:     `{"anthropic:parts":[{"qty":2,"sku":"A1"}]}`,
+		},
+		{
+			name:     "empty_part",
+			response: map[string]any{converters.FunctionResponsePartsKey: []any{map[string]any{"text": "bolt"}, map[string]any{}}},
+			want:     `{"anthropic:parts":[{"text":"bolt"},{}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &genai.Content{Role: "user", Parts: []*genai.Part{{
+				FunctionResponse: &genai.FunctionResponse{ID: "call_123", Name: "list_parts", Response: tt.response},
+			}}}
+
+			messages, err := converters.ContentsToMessages([]*genai.Content{content})
+			if err != nil {
+				t.Fatalf("ContentsToMessages() error = %v", err)
+			}
+			result := messages[0].Content[0].OfToolResult
+			if len(result.Content) != 1 || result.Content[0].OfText == nil || result.Content[0].OfText.Text != tt.want {
+				t.Errorf("tool result content = %+v, want the JSON response %s", result.Content, tt.want)
+			}
+		})
+	}
+}
+
 func TestFunctionResponseToBlock_RequiresID(t *testing.T) {
 	// FunctionResponse.ID is required for proper tool call correlation.
 	// Missing ID should return an error, not fall back to Name.
@@ -598,14 +1001,21 @@ func TestSchemaToMap_AllFields(t *testing.T) {
 				Description: "Function with all schema fields",
 				Parameters: &genai.Schema{
 					Type:        "object",
+					Title:       "FullSchema",
 					Description: "Root object",
 					Properties: map[string]*genai.Schema{
 						"name": {
 							Type:        "STRING",
+							Title:       "Name",
 							Description: "User name",
 							MinLength:   &minLen,
 							MaxLength:   &maxLen,
 							Pattern:     "^[a-zA-Z]+$",
+							Example:     "Alice",
+						},
+						"kind": {
+							Type: "STRING",
+							Enum: []string{"person"},
 						},
 						"age": {
 							Type:     "INTEGER",
@@ -617,7 +1027,7 @@ func TestSchemaToMap_AllFields(t *testing.T) {
 							Type:     "ARRAY",
 							MinItems: &minItems,
 							MaxItems: &maxItems,
-							Items:    &genai.Schema{Type: "STRING"},
+							Items:    &genai.Schema{Type: "STRING", Title: "Tag", Description: "A lowercase tag"},
 						},
 						"status": {
 							Type: "STRING",
@@ -626,7 +1036,7 @@ func TestSchemaToMap_AllFields(t *testing.T) {
 						"metadata": {
 							Type: "OBJECT",
 							Properties: map[string]*genai.Schema{
-								"created": {Type: "STRING", Format: "date-time"},
+								"created": {Type: "STRING", Format: "date-time", Description: "Creation time"},
 							},
 						},
 					},
@@ -642,6 +1052,9 @@ func TestSchemaToMap_AllFields(t *testing.T) {
 	}
 
 	is := result[0].OfTool.InputSchema
+	if diff := cmp.Diff(map[string]any{"title": "FullSchema", "description": "Root object"}, is.ExtraFields); diff != "" {
+		t.Errorf("root schema keywords mismatch (-want +got):\n%s", diff)
+	}
 	props, ok := is.Properties.(map[string]any)
 	if !ok {
 		t.Fatalf("expected Properties to be map[string]any, got %T", is.Properties)
@@ -654,12 +1067,29 @@ func TestSchemaToMap_AllFields(t *testing.T) {
 	if nameSchema["type"] != "string" {
 		t.Errorf("name.type = %v, want 'string'", nameSchema["type"])
 	}
+	if nameSchema["title"] != "Name" || nameSchema["description"] != "User name" {
+		t.Errorf("name title, description = %v, %v, want 'Name', 'User name'", nameSchema["title"], nameSchema["description"])
+	}
 	if nameSchema["minLength"] != minLen {
 		t.Errorf("name.minLength = %v, want %v", nameSchema["minLength"], minLen)
 	}
 	if nameSchema["pattern"] != "^[a-zA-Z]+$" {
 		t.Errorf("name.pattern = %v, want '^[a-zA-Z]+$'", nameSchema["pattern"])
 	}
+	if diff := cmp.Diff([]any{"Alice"}, nameSchema["examples"]); diff != "" {
+		t.Errorf("name.examples mismatch (-want +got):\n%s", diff)
+	}
+
+	kindSchema, ok := props["kind"].(map[string]any)
+	if !ok {
+		t.Fatal("expected 'kind' property")
+	}
+	if kindSchema["const"] != "person" {
+		t.Errorf("kind.const = %v, want 'person'", kindSchema["const"])
+	}
+	if diff := cmp.Diff([]string{"person"}, kindSchema["enum"]); diff != "" {
+		t.Errorf("kind.enum mismatch (-want +got):\n%s", diff)
+	}
 
 	ageSchema, ok := props["age"].(map[string]any)
 	if !ok {
@@ -686,6 +1116,9 @@ func TestSchemaToMap_AllFields(t *testing.T) {
 	if items["type"] != "string" {
 		t.Errorf("tags.items.type = %v, want 'string'", items["type"])
 	}
+	if items["title"] != "Tag" || items["description"] != "A lowercase tag" {
+		t.Errorf("tags.items title, description = %v, %v, want 'Tag', 'A lowercase tag'", items["title"], items["description"])
+	}
 
 	statusSchema, ok := props["status"].(map[string]any)
 	if !ok {
@@ -710,6 +1143,193 @@ func TestSchemaToMap_AllFields(t *testing.T) {
 	if createdSchema["format"] != "date-time" {
 		t.Errorf("created.format = %v, want 'date-time'", createdSchema["format"])
 	}
+	if createdSchema["description"] != "Creation time" {
+		t.Errorf("created.description = %v, want 'Creation time'", createdSchema["description"])
+	}
+}
+
+func TestSchemaToMap_Format(t *testing.T) {
+	tool := &genai.Tool{
+		FunctionDeclarations: []*genai.FunctionDeclaration{{
+			Name: "format_func",
+			Parameters: &genai.Schema{
+				Type: "object",
+				Properties: map[string]*genai.Schema{
+					"created":     {Type: "STRING", Format: "date-time"},
+					"email":       {Type: "STRING", Format: "EMAIL"},
+					"updated":     {Type: "STRING", Format: "DATE_TIME"},
+					"unspecified": {Type: "STRING", Format: "FORMAT_UNSPECIFIED"},
+					"unknown":     {Type: "STRING", Format: "credit-card"},
+					"count":       {Type: "INTEGER", Format: "int64"},
+				},
+			},
+		}},
+	}
+
+	result := converters.ToolsToAnthropicTools([]*genai.Tool{tool})
+	props := result[0].OfTool.InputSchema.Properties.(map[string]any)
+
+	want := map[string]any{
+		"created":     "date-time",
+		"email":       "email",
+		"updated":     "date-time",
+		"unspecified": nil,
+		"unknown":     nil,
+		"count":       nil,
+	}
+	got := make(map[string]any)
+	for name := range want {
+		got[name] = props[name].(map[string]any)["format"]
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("formats mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMakeToolSchemaStrict(t *testing.T) {
+	jsonSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":      map[string]any{"type": "string"},
+			"options": map[string]any{"type": "object", "properties": map[string]any{"verbose": map[string]any{"type": "boolean"}}},
+		},
+	}
+	tools := converters.ToolsToAnthropicTools([]*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+		{
+			Name: "search",
+			Parameters: &genai.Schema{
+				Type: "OBJECT",
+				Properties: map[string]*genai.Schema{
+					"query": {Type: "STRING"},
+					"filters": {Type: "ARRAY", Items: &genai.Schema{
+						Type:       "OBJECT",
+						Properties: map[string]*genai.Schema{"field": {Type: "STRING"}, "value": {Type: "STRING"}},
+					}},
+				},
+				PropertyOrdering: []string{"query", "filters"},
+				Required:         []string{"query"},
+			},
+		},
+		{Name: "fetch", ParametersJsonSchema: jsonSchema},
+	}}})
+	for _, tool := range tools {
+		converters.MakeToolSchemaStrict(tool)
+	}
+
+	want := []string{
+		`{"properties":{"query":{"type":"string"},"filters":{"items":{"additionalProperties":false,"properties":{"field":{"type":"string"},"value":{"type":"string"}},"required":["field","value"],"type":"object"},"type":"array"}},"required":["query","filters"],"type":"object","additionalProperties":false}`,
+		`{"properties":{"id":{"type":"string"},"options":{"additionalProperties":false,"properties":{"verbose":{"type":"boolean"}},"required":["verbose"],"type":"object"}},"required":["id","options"],"type":"object","additionalProperties":false}`,
+	}
+	for i, tool := range tools {
+		got, err := json.Marshal(tool.OfTool.InputSchema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want[i] {
+			t.Errorf("strict input schema of %s =\n%s\nwant\n%s", tool.OfTool.Name, got, want[i])
+		}
+	}
+
+	options := jsonSchema["properties"].(map[string]any)["options"].(map[string]any)
+	if _, ok := options["required"]; ok {
+		t.Error("MakeToolSchemaStrict modified the ParametersJsonSchema of the declaration")
+	}
+}
+
+func TestMakeToolSchemaStrict_Definitions(t *testing.T) {
+	address := map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}}
+	jsonSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"home": map[string]any{"$ref": "#/$defs/address"},
+			"work": map[string]any{"$ref": "#/definitions/office"},
+		},
+		"$defs": map[string]any{"address": address},
+		"definitions": map[string]any{"office": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"address": map[string]any{"$ref": "#/$defs/address"}},
+			"$defs":      map[string]any{"floor": map[string]any{"type": "object", "properties": map[string]any{"level": map[string]any{"type": "integer"}}}},
+		}},
+	}
+	tools := converters.ToolsToAnthropicTools([]*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+		{Name: "route", ParametersJsonSchema: jsonSchema},
+	}}})
+	converters.MakeToolSchemaStrict(tools[0])
+
+	data, err := json.Marshal(tools[0].OfTool.InputSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	closedAddress := map[string]any{"type": "object", "additionalProperties": false, "required": []any{"city"}, "properties": map[string]any{"city": map[string]any{"type": "string"}}}
+	want := map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"required":             []any{"home", "work"},
+		"properties": map[string]any{
+			"home": map[string]any{"$ref": "#/$defs/address"},
+			"work": map[string]any{"$ref": "#/definitions/office"},
+		},
+		"$defs": map[string]any{"address": closedAddress},
+		"definitions": map[string]any{"office": map[string]any{
+			"type":                 "object",
+			"additionalProperties": false,
+			"required":             []any{"address"},
+			"properties":           map[string]any{"address": map[string]any{"$ref": "#/$defs/address"}},
+			"$defs": map[string]any{"floor": map[string]any{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []any{"level"},
+				"properties":           map[string]any{"level": map[string]any{"type": "integer"}},
+			}},
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("strict input schema mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, ok := address["required"]; ok {
+		t.Error("MakeToolSchemaStrict modified the definitions of the declaration")
+	}
+}
+
+func TestEncodeToolSchemas(t *testing.T) {
+	tools := converters.ToolsToAnthropicTools([]*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+		{
+			Name: "search",
+			Parameters: &genai.Schema{
+				Type:             "OBJECT",
+				Title:            "Search",
+				Description:      "Search parameters",
+				Properties:       map[string]*genai.Schema{"query": {Type: "STRING"}, "limit": {Type: "INTEGER"}},
+				PropertyOrdering: []string{"query", "limit"},
+			},
+		},
+		{Name: "fetch", Parameters: &genai.Schema{Type: "OBJECT", Properties: map[string]*genai.Schema{"id": {Type: "STRING"}}}},
+	}}})
+	converters.MakeToolSchemaStrict(tools[0])
+	if err := converters.EncodeToolSchemas(tools); err != nil {
+		t.Fatalf("EncodeToolSchemas() error = %v", err)
+	}
+
+	want := []string{
+		`{"additionalProperties":false,"description":"Search parameters","properties":{"query":{"type":"string"},"limit":{"type":"integer"}},"required":["query","limit"],"title":"Search","type":"object"}`,
+		`{"properties":{"id":{"type":"string"}},"type":"object"}`,
+	}
+	for i, tool := range tools {
+		for range 10 {
+			got, err := json.Marshal(tool.OfTool.InputSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want[i] {
+				t.Fatalf("encoded input schema of %s =\n%s\nwant\n%s", tool.OfTool.Name, got, want[i])
+			}
+		}
+	}
 }
 
 func TestSchemaToMap_AnyOf(t *testing.T) {
@@ -876,3 +1496,1033 @@ func TestContentBlockToGenaiPart_WebSearchToolResult(t *testing.T) {
 		t.Errorf("results[0].url = %q, want 'https://example.com'", results[0]["url"])
 	}
 }
+
+func TestMessageToLLMResponse_Refusal(t *testing.T) {
+	msgJSON := `{
+		"content": [{"type": "text", "text": "I can't help with that."}],
+		"stop_reason": "refusal",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`
+
+	var msg anthropic.Message
+	if err := msg.UnmarshalJSON([]byte(msgJSON)); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+
+	resp, err := converters.MessageToLLMResponse(&msg)
+	if err != nil {
+		t.Fatalf("MessageToLLMResponse() error = %v", err)
+	}
+
+	if resp.FinishReason != genai.FinishReasonSafety {
+		t.Errorf("FinishReason = %v, want %v", resp.FinishReason, genai.FinishReasonSafety)
+	}
+	if len(resp.Content.Parts) != 1 || resp.Content.Parts[0].Text != "I can't help with that." {
+		t.Errorf("Content.Parts = %+v, want the refusal text", resp.Content.Parts)
+	}
+}
+
+func TestContentsToMessages_ParallelToolResultsMerged(t *testing.T) {
+	ids := []string{"toolu_1", "toolu_2", "toolu_3"}
+
+	contents := []*genai.Content{
+		genai.NewContentFromText("Check the weather in three cities", "user"),
+		{
+			Role: "model",
+			Parts: []*genai.Part{
+				{FunctionCall: &genai.FunctionCall{ID: ids[0], Name: "get_weather", Args: map[string]any{"city": "London"}}},
+				{FunctionCall: &genai.FunctionCall{ID: ids[1], Name: "get_weather", Args: map[string]any{"city": "Paris"}}},
+				{FunctionCall: &genai.FunctionCall{ID: ids[2], Name: "get_weather", Args: map[string]any{"city": "Tokyo"}}},
+			},
+		},
+	}
+	// Each tool result arrives as its own content, as produced by parallel tool execution.
+	for _, id := range ids {
+		contents = append(contents, &genai.Content{
+			Role: "user",
+			Parts: []*genai.Part{
+				{FunctionResponse: &genai.FunctionResponse{ID: id, Name: "get_weather", Response: map[string]any{"temp": 20}}},
+			},
+		})
+	}
+
+	messages, err := converters.ContentsToMessages(contents)
+	if err != nil {
+		t.Fatalf("ContentsToMessages() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+
+	var toolUseIDs []string
+	for _, block := range messages[1].Content {
+		if block.OfToolUse == nil {
+			t.Fatalf("expected only tool_use blocks in assistant message, got %+v", block)
+		}
+		toolUseIDs = append(toolUseIDs, block.OfToolUse.ID)
+	}
+
+	results := messages[2]
+	if results.Role != anthropic.MessageParamRoleUser {
+		t.Errorf("expected tool results in a user message, got %q", results.Role)
+	}
+	var toolResultIDs []string
+	for _, block := range results.Content {
+		if block.OfToolResult == nil {
+			t.Fatalf("expected only tool_result blocks in user message, got %+v", block)
+		}
+		toolResultIDs = append(toolResultIDs, block.OfToolResult.ToolUseID)
+	}
+
+	if diff := cmp.Diff(ids, toolUseIDs); diff != "" {
+		t.Errorf("tool_use ids mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(toolUseIDs, toolResultIDs); diff != "" {
+		t.Errorf("tool_result ids do not match tool_use ids (-want +got):\n%s", diff)
+	}
+}
+
+func TestContentsToMessages_SyntheticToolIDs(t *testing.T) {
+	contents := []*genai.Content{
+		genai.NewContentFromText("Weather in London and Paris?", "user"),
+		{
+			Role: "model",
+			Parts: []*genai.Part{
+				{FunctionCall: &genai.FunctionCall{Name: "get_weather", Args: map[string]any{"city": "London"}}},
+				{FunctionCall: &genai.FunctionCall{Name: "get_weather", Args: map[string]any{"city": "Paris"}}},
+			},
+		},
+		{
+			Role: "user",
+			Parts: []*genai.Part{
+				{FunctionResponse: &genai.FunctionResponse{Name: "get_weather", Response: map[string]any{"temp": 15}}},
+				{FunctionResponse: &genai.FunctionResponse{Name: "get_weather", Response: map[string]any{"temp": 20}}},
+			},
+		},
+	}
+
+	convert := func() (useIDs, resultIDs []string) {
+		messages, err := converters.ContentsToMessages(contents)
+		if err != nil {
+			t.Fatalf("ContentsToMessages() error = %v", err)
+		}
+		for _, block := range messages[1].Content {
+			useIDs = append(useIDs, block.OfToolUse.ID)
+		}
+		for _, block := range messages[2].Content {
+			resultIDs = append(resultIDs, block.OfToolResult.ToolUseID)
+		}
+		return useIDs, resultIDs
+	}
+
+	useIDs, resultIDs := convert()
+	want := []string{"toolu_adk_1_0", "toolu_adk_1_1"}
+	if diff := cmp.Diff(want, useIDs); diff != "" {
+		t.Errorf("tool_use ids mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want, resultIDs); diff != "" {
+		t.Errorf("tool_result ids mismatch (-want +got):\n%s", diff)
+	}
+
+	// IDs are stable across conversions and the input is left untouched.
+	againUse, againResult := convert()
+	if diff := cmp.Diff(useIDs, againUse); diff != "" {
+		t.Errorf("tool_use ids not stable (-first +second):\n%s", diff)
+	}
+	if diff := cmp.Diff(resultIDs, againResult); diff != "" {
+		t.Errorf("tool_result ids not stable (-first +second):\n%s", diff)
+	}
+	if id := contents[1].Parts[0].FunctionCall.ID; id != "" {
+		t.Errorf("input FunctionCall.ID was modified to %q", id)
+	}
+	if id := contents[2].Parts[0].FunctionResponse.ID; id != "" {
+		t.Errorf("input FunctionResponse.ID was modified to %q", id)
+	}
+}
+
+func TestContentsToMessages_SyntheticToolIDsSkipAnsweredCalls(t *testing.T) {
+	contents := []*genai.Content{
+		genai.NewContentFromText("Search twice", "user"),
+		{Role: "model", Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "toolu_real", Name: "search"}}}},
+		{Role: "user", Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{ID: "toolu_real", Name: "search"}}}},
+		{Role: "model", Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{Name: "search"}}}},
+		{Role: "user", Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{Name: "search"}}}},
+	}
+
+	messages, err := converters.ContentsToMessages(contents)
+	if err != nil {
+		t.Fatalf("ContentsToMessages() error = %v", err)
+	}
+	if got := messages[4].Content[0].OfToolResult.ToolUseID; got != "toolu_adk_3_0" {
+		t.Errorf("tool_result id = %q, want %q", got, "toolu_adk_3_0")
+	}
+}
+
+func TestMessageToLLMResponse_ToolOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "tool_use_only", content: `[{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"London"}}]`},
+		{name: "empty_text_before_tool_use", content: `[{"type":"text","text":""},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"London"}}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg anthropic.Message
+			msgJSON := `{"content":` + tt.content + `,"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`
+			if err := msg.UnmarshalJSON([]byte(msgJSON)); err != nil {
+				t.Fatalf("failed to unmarshal message: %v", err)
+			}
+
+			resp, err := converters.MessageToLLMResponse(&msg)
+			if err != nil {
+				t.Fatalf("MessageToLLMResponse() error = %v", err)
+			}
+			want := &genai.Content{Role: "model", Parts: []*genai.Part{{
+				FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "get_weather", Args: map[string]any{"city": "London"}},
+			}}}
+			if diff := cmp.Diff(want, resp.Content); diff != "" {
+				t.Errorf("Content mismatch (-want +got):\n%s", diff)
+			}
+			if resp.FinishReason != genai.FinishReasonStop {
+				t.Errorf("FinishReason = %v, want %v", resp.FinishReason, genai.FinishReasonStop)
+			}
+		})
+	}
+}
+
+func TestMessageToLLMResponse_MalformedToolInput(t *testing.T) {
+	msgJSON := `{
+		"content": [{
+			"type": "tool_use",
+			"id": "toolu_1",
+			"name": "get_weather",
+			"input": "{\"city\": \"Lon"
+		}],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`
+
+	var msg anthropic.Message
+	if err := msg.UnmarshalJSON([]byte(msgJSON)); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+
+	resp, err := converters.MessageToLLMResponse(&msg)
+	if err != nil {
+		t.Fatalf("MessageToLLMResponse() error = %v", err)
+	}
+
+	if resp.FinishReason != genai.FinishReasonMalformedFunctionCall {
+		t.Errorf("FinishReason = %v, want %v", resp.FinishReason, genai.FinishReasonMalformedFunctionCall)
+	}
+	call := resp.Content.Parts[0].FunctionCall
+	if call == nil {
+		t.Fatal("expected FunctionCall part")
+	}
+	want := map[string]any{converters.RawToolInputKey: `"{\"city\": \"Lon"`}
+	if diff := cmp.Diff(want, call.Args); diff != "" {
+		t.Errorf("Args mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPartToContentBlock_PlainTextDocument(t *testing.T) {
+	part := &genai.Part{
+		InlineData: &genai.Blob{
+			Data:        []byte("The grass is green. The sky is blue."),
+			MIMEType:    "text/plain; charset=utf-8",
+			DisplayName: "facts.txt",
+		},
+	}
+
+	block, err := converters.PartToContentBlock(part)
+	if err != nil {
+		t.Fatalf("PartToContentBlock() error = %v", err)
+	}
+	doc := block.OfDocument
+	if doc == nil || doc.Source.OfText == nil {
+		t.Fatalf("expected plain text document block, got %+v", block)
+	}
+	if doc.Source.OfText.Data != "The grass is green. The sky is blue." {
+		t.Errorf("Source.Data = %q, want document text", doc.Source.OfText.Data)
+	}
+	if !doc.Citations.Enabled.Value {
+		t.Error("expected citations to be enabled")
+	}
+	if doc.Title.Value != "facts.txt" {
+		t.Errorf("Title = %q, want %q", doc.Title.Value, "facts.txt")
+	}
+}
+
+func TestPartToContentBlock_PlainTextDocumentURL(t *testing.T) {
+	part := genai.NewPartFromURI("https://example.com/facts.txt", "text/plain")
+
+	_, err := converters.PartToContentBlock(part)
+	if err == nil || !strings.Contains(err.Error(), "provide them as inline data") {
+		t.Fatalf("PartToContentBlock() error = %v, want error explaining inline requirement", err)
+	}
+}
+
+func TestPartToContentBlock_DocumentChunks(t *testing.T) {
+	part := &genai.Part{
+		InlineData: &genai.Blob{
+			Data:        []byte(`["First passage.", "Second passage."]`),
+			MIMEType:    converters.DocumentChunksMIMEType,
+			DisplayName: "Handbook",
+		},
+	}
+
+	block, err := converters.PartToContentBlock(part)
+	if err != nil {
+		t.Fatalf("PartToContentBlock() error = %v", err)
+	}
+	doc := block.OfDocument
+	if doc == nil || doc.Source.OfContent == nil {
+		t.Fatalf("expected custom content document block, got %+v", block)
+	}
+
+	var got []string
+	for _, item := range doc.Source.OfContent.Content.OfContentBlockSourceContent {
+		got = append(got, item.OfText.Text)
+	}
+	if diff := cmp.Diff([]string{"First passage.", "Second passage."}, got); diff != "" {
+		t.Errorf("chunks mismatch (-want +got):\n%s", diff)
+	}
+	if !doc.Citations.Enabled.Value {
+		t.Error("expected citations to be enabled")
+	}
+	if doc.Title.Value != "Handbook" {
+		t.Errorf("Title = %q, want %q", doc.Title.Value, "Handbook")
+	}
+
+	part.InlineData.Data = []byte(`{"not": "an array"}`)
+	if _, err := converters.PartToContentBlock(part); err == nil {
+		t.Error("expected error for malformed document chunks")
+	}
+}
+
+func TestMessageToLLMResponse_ContentBlockCitation(t *testing.T) {
+	msgJSON := `{
+		"content": [{
+			"type": "text",
+			"text": "The handbook says so.",
+			"citations": [{
+				"type": "content_block_location",
+				"document_index": 0,
+				"document_title": "Handbook",
+				"start_block_index": 1,
+				"end_block_index": 2,
+				"cited_text": "Second passage."
+			}]
+		}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`
+
+	var msg anthropic.Message
+	if err := msg.UnmarshalJSON([]byte(msgJSON)); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+
+	resp, err := converters.MessageToLLMResponse(&msg)
+	if err != nil {
+		t.Fatalf("MessageToLLMResponse() error = %v", err)
+	}
+
+	want := []*genai.Citation{{Title: "Handbook", StartIndex: 1, EndIndex: 2}}
+	if diff := cmp.Diff(want, resp.CitationMetadata.Citations); diff != "" {
+		t.Errorf("Citations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPartToContentBlock_SearchResult(t *testing.T) {
+	part := &genai.Part{
+		InlineData: &genai.Blob{
+			Data:     []byte(`{"source":"https://example.com/handbook","title":"Handbook","content":["First passage.","Second passage."]}`),
+			MIMEType: converters.SearchResultMIMEType,
+		},
+	}
+
+	block, err := converters.PartToContentBlock(part)
+	if err != nil {
+		t.Fatalf("PartToContentBlock() error = %v", err)
+	}
+	result := block.OfSearchResult
+	if result == nil {
+		t.Fatalf("expected search result block, got %+v", block)
+	}
+	if result.Source != "https://example.com/handbook" || result.Title != "Handbook" {
+		t.Errorf("Source, Title = %q, %q, want %q, %q", result.Source, result.Title, "https://example.com/handbook", "Handbook")
+	}
+	var got []string
+	for _, text := range result.Content {
+		got = append(got, text.Text)
+	}
+	if diff := cmp.Diff([]string{"First passage.", "Second passage."}, got); diff != "" {
+		t.Errorf("passages mismatch (-want +got):\n%s", diff)
+	}
+	if !result.Citations.Enabled.Value {
+		t.Error("expected citations to be enabled")
+	}
+
+	for _, data := range []string{`["not", "an object"]`, `{"source":"s","title":"t","content":[]}`, `{"title":"t","content":["p"]}`} {
+		part.InlineData.Data = []byte(data)
+		if _, err := converters.PartToContentBlock(part); err == nil {
+			t.Errorf("PartToContentBlock(%s) expected error", data)
+		}
+	}
+}
+
+func TestFunctionResponseToBlock_SearchResult(t *testing.T) {
+	part := &genai.Part{FunctionResponse: &genai.FunctionResponse{
+		ID:   "toolu_1",
+		Name: "search",
+		Response: map[string]any{converters.FunctionResponsePartsKey: []*genai.Part{{
+			InlineData: &genai.Blob{
+				Data:     []byte(`{"source":"https://example.com/handbook","title":"Handbook","content":["First passage."]}`),
+				MIMEType: converters.SearchResultMIMEType,
+			},
+		}}},
+	}}
+
+	block, err := converters.PartToContentBlock(part)
+	if err != nil {
+		t.Fatalf("PartToContentBlock() error = %v", err)
+	}
+	content := block.OfToolResult.Content
+	if len(content) != 1 || content[0].OfSearchResult == nil {
+		t.Fatalf("tool result content = %+v, want a search result", content)
+	}
+}
+
+func TestMessageToLLMResponse_SearchResultCitation(t *testing.T) {
+	msgJSON := `{
+		"content": [{
+			"type": "text",
+			"text": "The handbook says so.",
+			"citations": [{
+				"type": "search_result_location",
+				"search_result_index": 0,
+				"source": "https://example.com/handbook",
+				"title": "Handbook",
+				"start_block_index": 1,
+				"end_block_index": 2,
+				"cited_text": "Second passage."
+			}]
+		}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`
+
+	var msg anthropic.Message
+	if err := msg.UnmarshalJSON([]byte(msgJSON)); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+
+	resp, err := converters.MessageToLLMResponse(&msg)
+	if err != nil {
+		t.Fatalf("MessageToLLMResponse() error = %v", err)
+	}
+
+	want := []*genai.Citation{{Title: "Handbook", URI: "https://example.com/handbook", StartIndex: 1, EndIndex: 2}}
+	if diff := cmp.Diff(want, resp.CitationMetadata.Citations); diff != "" {
+		t.Errorf("Citations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFunctionDeclarationToTool_AdditionalProperties(t *testing.T) {
+	closed := &jsonschema.Schema{Not: &jsonschema.Schema{}} // the "false" schema
+
+	tests := []struct {
+		name   string
+		schema any
+		want   string
+	}{
+		{
+			name: "jsonschema closed object",
+			schema: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"name": {Type: "string"},
+					"address": {
+						Type:                 "object",
+						Properties:           map[string]*jsonschema.Schema{"city": {Type: "string"}},
+						AdditionalProperties: closed,
+					},
+				},
+				AdditionalProperties: closed,
+			},
+			want: `{"properties":{"address":{"additionalProperties":false,"properties":{"city":{"type":"string"}},"type":"object"},"name":{"type":"string"}},"type":"object","additionalProperties":false}`,
+		},
+		{
+			name: "map closed object",
+			schema: map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"name": map[string]any{"type": "string"}},
+				"additionalProperties": false,
+			},
+			want: `{"properties":{"name":{"type":"string"}},"type":"object","additionalProperties":false}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := converters.FunctionDeclarationToTool(&genai.FunctionDeclaration{Name: "f", ParametersJsonSchema: tt.schema})
+			got, err := json.Marshal(tool.OfTool.InputSchema)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("InputSchema = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaToMap_OneOf(t *testing.T) {
+	testJSONSchemaComposition(t, "oneOf", &jsonschema.Schema{
+		OneOf: []*jsonschema.Schema{
+			{Type: "object", Properties: map[string]*jsonschema.Schema{"email": {Type: "string"}}},
+			{Type: "object", Properties: map[string]*jsonschema.Schema{"phone": {Type: "string"}}},
+		},
+	})
+}
+
+func TestJSONSchemaToMap_AllOf(t *testing.T) {
+	testJSONSchemaComposition(t, "allOf", &jsonschema.Schema{
+		AllOf: []*jsonschema.Schema{
+			{Type: "object", Properties: map[string]*jsonschema.Schema{"id": {Type: "string"}}},
+			{Type: "object", Properties: map[string]*jsonschema.Schema{"name": {Type: "string"}}},
+		},
+	})
+}
+
+// testJSONSchemaComposition asserts that the "value" property converted from
+// valueSchema carries two entries under the given composition keyword.
+func testJSONSchemaComposition(t *testing.T, keyword string, valueSchema *jsonschema.Schema) {
+	t.Helper()
+
+	fd := &genai.FunctionDeclaration{
+		Name: keyword + "_func",
+		ParametersJsonSchema: &jsonschema.Schema{
+			Type:       "object",
+			Properties: map[string]*jsonschema.Schema{"value": valueSchema},
+		},
+	}
+
+	result := converters.FunctionDeclarationToTool(fd)
+	props, ok := result.OfTool.InputSchema.Properties.(map[string]any)
+	if !ok {
+		t.Fatalf("expected Properties to be map[string]any, got %T", result.OfTool.InputSchema.Properties)
+	}
+	value, ok := props["value"].(map[string]any)
+	if !ok {
+		t.Fatal("expected 'value' property")
+	}
+
+	entries, ok := value[keyword].([]map[string]any)
+	if !ok {
+		t.Fatalf("expected %q to be []map[string]any, got %T", keyword, value[keyword])
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 %s entries, got %d", keyword, len(entries))
+	}
+	for i, entry := range entries {
+		if _, ok := entry["properties"].(map[string]any); !ok {
+			t.Errorf("%s[%d] = %v, want nested properties", keyword, i, entry)
+		}
+	}
+}
+
+func TestFunctionDeclarationToTool_RefsAndDefs(t *testing.T) {
+	const want = `{"properties":{"root":{"$ref":"#/$defs/node"}},"required":["root"],"type":"object","$defs":{"node":{"properties":{"children":{"items":{"$ref":"#/$defs/node"},"type":"array"},"value":{"type":"string"}},"type":"object"}}}`
+
+	tests := []struct {
+		name   string
+		schema any
+	}{
+		{
+			name: "map",
+			schema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"root": map[string]any{"$ref": "#/$defs/node"}},
+				"required":   []any{"root"},
+				"$defs": map[string]any{
+					"node": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"value":    map[string]any{"type": "string"},
+							"children": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/node"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "jsonschema",
+			schema: &jsonschema.Schema{
+				Type:       "object",
+				Properties: map[string]*jsonschema.Schema{"root": {Ref: "#/$defs/node"}},
+				Required:   []string{"root"},
+				Defs: map[string]*jsonschema.Schema{
+					"node": {
+						Type: "object",
+						Properties: map[string]*jsonschema.Schema{
+							"value":    {Type: "string"},
+							"children": {Type: "array", Items: &jsonschema.Schema{Ref: "#/$defs/node"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := converters.FunctionDeclarationToTool(&genai.FunctionDeclaration{Name: "tree", ParametersJsonSchema: tt.schema})
+			got, err := json.Marshal(tool.OfTool.InputSchema)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("InputSchema = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestFunctionDeclarationToTool_PropertyOrdering(t *testing.T) {
+	newDecl := func(ordering []string) *genai.FunctionDeclaration {
+		return &genai.FunctionDeclaration{
+			Name: "ordered",
+			Parameters: &genai.Schema{
+				Type: "OBJECT",
+				Properties: map[string]*genai.Schema{
+					"zeta":  {Type: "STRING"},
+					"alpha": {Type: "STRING"},
+					"mid": {
+						Type: "OBJECT",
+						Properties: map[string]*genai.Schema{
+							"y": {Type: "STRING"},
+							"x": {Type: "STRING"},
+						},
+						PropertyOrdering: []string{"y", "x"},
+					},
+				},
+				PropertyOrdering: ordering,
+			},
+		}
+	}
+
+	marshal := func(fd *genai.FunctionDeclaration) string {
+		b, err := json.Marshal(converters.FunctionDeclarationToTool(fd))
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		return string(b)
+	}
+
+	t.Run("honors PropertyOrdering", func(t *testing.T) {
+		got := marshal(newDecl([]string{"zeta", "mid"}))
+		want := `"properties":{"zeta":{"type":"string"},"mid":{"properties":{"y":{"type":"string"},"x":{"type":"string"}},"type":"object"},"alpha":{"type":"string"}}`
+		if !strings.Contains(got, want) {
+			t.Errorf("tool = %s, want properties %s", got, want)
+		}
+	})
+
+	t.Run("stable without PropertyOrdering", func(t *testing.T) {
+		first := marshal(newDecl(nil))
+		for range 10 {
+			if got := marshal(newDecl(nil)); got != first {
+				t.Fatalf("tool serialization is not stable:\n%s\n%s", first, got)
+			}
+		}
+		if !strings.Contains(first, `"properties":{"alpha":`) {
+			t.Errorf("tool = %s, want properties sorted by name", first)
+		}
+	})
+}
+
+func TestRestoreJSONPrefill(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		finish   genai.FinishReason
+		wantText string
+		wantErr  bool
+	}{
+		{name: "valid", text: `"a":1}`, finish: genai.FinishReasonStop, wantText: `{"a":1}`},
+		{name: "trailing fence", text: "\"a\":1}\n```\n", finish: genai.FinishReasonStop, wantText: `{"a":1}`},
+		{name: "invalid", text: `"a":1} trailing`, finish: genai.FinishReasonStop, wantErr: true},
+		{name: "truncated", text: `"a":`, finish: genai.FinishReasonMaxTokens, wantText: `{"a":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &model.LLMResponse{
+				Content: &genai.Content{Role: "model", Parts: []*genai.Part{
+					{Text: "thinking", Thought: true},
+					{Text: tt.text},
+				}},
+				FinishReason: tt.finish,
+			}
+			err := converters.RestoreJSONPrefill(resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RestoreJSONPrefill() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := resp.Content.Parts[1].Text; got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			if got := resp.Content.Parts[0].Text; got != "thinking" {
+				t.Errorf("thought text = %q, want unchanged", got)
+			}
+		})
+	}
+}
+
+func TestMessageToLLMResponse_MCPToolBlocks(t *testing.T) {
+	msgJSON := `{
+		"content": [
+			{"type": "mcp_tool_use", "id": "mcptoolu_1", "name": "echo", "server_name": "example", "input": {"param1": "hi"}},
+			{"type": "mcp_tool_result", "tool_use_id": "mcptoolu_1", "is_error": false, "content": [{"type": "text", "text": "hi"}]},
+			{"type": "mcp_tool_result", "tool_use_id": "mcptoolu_2", "is_error": true, "content": "server unavailable"}
+		],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`
+
+	var msg anthropic.Message
+	if err := msg.UnmarshalJSON([]byte(msgJSON)); err != nil {
+		t.Fatalf("failed to unmarshal message: %v", err)
+	}
+
+	resp, err := converters.MessageToLLMResponse(&msg)
+	if err != nil {
+		t.Fatalf("MessageToLLMResponse() error = %v", err)
+	}
+
+	if len(resp.Content.Parts) != 3 {
+		t.Fatalf("got %d parts, want 3", len(resp.Content.Parts))
+	}
+	for i, part := range resp.Content.Parts {
+		if part.FunctionCall != nil || part.FunctionResponse != nil {
+			t.Errorf("part %d = %+v, want no function call or response", i, part)
+		}
+		if part.InlineData == nil || part.InlineData.MIMEType != converters.ServerToolBlockMIMEType {
+			t.Fatalf("part %d = %+v, want a server tool block", i, part)
+		}
+	}
+
+	// The blocks are sent back unchanged, in an assistant turn
+	resp.Content.Parts = append(resp.Content.Parts, genai.NewPartFromText("The echo said hi."))
+	messages, err := converters.ContentsToMessages([]*genai.Content{resp.Content})
+	if err != nil {
+		t.Fatalf("ContentsToMessages() error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].Role != anthropic.MessageParamRoleAssistant {
+		t.Fatalf("messages = %+v, want one assistant message", messages)
+	}
+	got, err := json.Marshal(messages[0].Content)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, want := range []string{
+		`{"type":"mcp_tool_use","id":"mcptoolu_1","name":"echo","server_name":"example","input":{"param1":"hi"}}`,
+		`{"type":"mcp_tool_result","tool_use_id":"mcptoolu_2","is_error":true,"content":"server unavailable"}`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("content = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestPartToContentBlock_DataURI(t *testing.T) {
+	pngData := []byte("\x89PNG\r\n\x1a\nfake")
+	encoded := base64.StdEncoding.EncodeToString(pngData)
+
+	t.Run("base64 image", func(t *testing.T) {
+		part := &genai.Part{FileData: &genai.FileData{FileURI: "data:image/png;base64," + encoded}}
+		block, err := converters.PartToContentBlock(part)
+		if err != nil {
+			t.Fatalf("PartToContentBlock() error = %v", err)
+		}
+		if block.OfImage == nil || block.OfImage.Source.OfBase64 == nil {
+			t.Fatalf("expected base64 image block, got %+v", block)
+		}
+		src := block.OfImage.Source.OfBase64
+		if src.MediaType != anthropic.Base64ImageSourceMediaTypeImagePNG || src.Data != encoded {
+			t.Errorf("Source = %s %q, want image/png %q", src.MediaType, src.Data, encoded)
+		}
+	})
+
+	tests := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{name: "base64 text with charset", uri: "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte("héllo")), want: "héllo"},
+		{name: "percent-encoded text", uri: "data:text/plain,Hello%2C%20world", want: "Hello, world"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := converters.PartToContentBlock(&genai.Part{FileData: &genai.FileData{FileURI: tt.uri}})
+			if err != nil {
+				t.Fatalf("PartToContentBlock() error = %v", err)
+			}
+			if block.OfDocument == nil || block.OfDocument.Source.OfText == nil {
+				t.Fatalf("expected plain text document block, got %+v", block)
+			}
+			if got := block.OfDocument.Source.OfText.Data; got != tt.want {
+				t.Errorf("Source.Data = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("MIME type from FileData", func(t *testing.T) {
+		part := &genai.Part{FileData: &genai.FileData{FileURI: "data:;base64," + encoded, MIMEType: "image/png"}}
+		block, err := converters.PartToContentBlock(part)
+		if err != nil {
+			t.Fatalf("PartToContentBlock() error = %v", err)
+		}
+		if block.OfImage == nil {
+			t.Fatalf("expected image block, got %+v", block)
+		}
+	})
+
+	t.Run("invalid base64", func(t *testing.T) {
+		part := &genai.Part{FileData: &genai.FileData{FileURI: "data:image/png;base64,!!!"}}
+		if _, err := converters.PartToContentBlock(part); err == nil {
+			t.Error("PartToContentBlock() error = nil, want error")
+		}
+	})
+}
+
+func TestPartToContentBlock_CloudStorageURI(t *testing.T) {
+	part := &genai.Part{FileData: &genai.FileData{FileURI: "gs://bucket/image.png", MIMEType: "image/png"}}
+	_, err := converters.PartToContentBlock(part)
+	if err == nil || !strings.Contains(err.Error(), "signed HTTPS URL") {
+		t.Errorf("PartToContentBlock() error = %v, want Cloud Storage error", err)
+	}
+}
+
+func TestContentsToMessages_DropsWhitespaceText(t *testing.T) {
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{{Text: "Hello"}, {Text: " \n\t"}}},
+		{Role: "model", Parts: []*genai.Part{{Text: "  "}, {Text: "\n"}}},
+		{Role: "user", Parts: []*genai.Part{{Text: "Still there?"}}},
+	}
+
+	messages, err := converters.ContentsToMessages(contents)
+	if err != nil {
+		t.Fatalf("ContentsToMessages() error = %v", err)
+	}
+
+	// The all-whitespace model turn is dropped and the user turns merge.
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	var texts []string
+	for _, block := range messages[0].Content {
+		texts = append(texts, block.OfText.Text)
+	}
+	if diff := cmp.Diff([]string{"Hello", "Still there?"}, texts); diff != "" {
+		t.Errorf("texts mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContentsToMessages_NilParts(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []*genai.Content
+		want     []anthropic.MessageParamRole
+		wantText [][]string
+	}{
+		{
+			name: "all_nil_parts_dropped",
+			contents: []*genai.Content{
+				genai.NewContentFromText("Hello", "user"),
+				{Role: "model", Parts: []*genai.Part{nil, nil}},
+				genai.NewContentFromText("Still there?", "user"),
+			},
+			want:     []anthropic.MessageParamRole{anthropic.MessageParamRoleUser},
+			wantText: [][]string{{"Hello", "Still there?"}},
+		},
+		{
+			name: "all_nil_parts_without_role",
+			contents: []*genai.Content{
+				genai.NewContentFromText("Hello", "user"),
+				{Parts: []*genai.Part{nil}},
+			},
+			want:     []anthropic.MessageParamRole{anthropic.MessageParamRoleUser},
+			wantText: [][]string{{"Hello"}},
+		},
+		{
+			name: "mixed_nil_and_valid_parts",
+			contents: []*genai.Content{
+				{Role: "user", Parts: []*genai.Part{nil, {Text: "Hello"}, nil}},
+				{Role: "model", Parts: []*genai.Part{{Text: "Hi!"}, nil}},
+				{Role: "user", Parts: []*genai.Part{nil, {Text: "How are you?"}}},
+			},
+			want:     []anthropic.MessageParamRole{anthropic.MessageParamRoleUser, anthropic.MessageParamRoleAssistant, anthropic.MessageParamRoleUser},
+			wantText: [][]string{{"Hello"}, {"Hi!"}, {"How are you?"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := converters.ContentsToMessages(tt.contents)
+			if err != nil {
+				t.Fatalf("ContentsToMessages() error = %v", err)
+			}
+			var roles []anthropic.MessageParamRole
+			var texts [][]string
+			for _, msg := range messages {
+				roles = append(roles, msg.Role)
+				var msgTexts []string
+				for _, block := range msg.Content {
+					msgTexts = append(msgTexts, block.OfText.Text)
+				}
+				texts = append(texts, msgTexts)
+			}
+			if diff := cmp.Diff(tt.want, roles); diff != "" {
+				t.Errorf("roles mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantText, texts); diff != "" {
+				t.Errorf("texts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestContentsToMessages_ToolRole(t *testing.T) {
+	contents := []*genai.Content{
+		genai.NewContentFromText("Look it up", "user"),
+		{Role: "model", Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "lookup"}}}},
+		{Role: "tool", Parts: []*genai.Part{{Text: "lookup finished"}}},
+	}
+
+	messages, err := converters.ContentsToMessages(contents)
+	if err != nil {
+		t.Fatalf("ContentsToMessages() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(messages))
+	}
+	if got := messages[2].Role; got != anthropic.MessageParamRoleUser {
+		t.Errorf("tool content role = %q, want %q", got, anthropic.MessageParamRoleUser)
+	}
+}
+
+func TestContentsToMessages_ThinkingBeforeToolUse(t *testing.T) {
+	thought := &genai.Part{Text: "I should look it up.", Thought: true, ThoughtSignature: []byte("sig1")}
+	call := &genai.Part{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "lookup", Args: map[string]any{"q": "x"}}}
+	text := genai.NewPartFromText("Let me check.")
+
+	tests := []struct {
+		name  string
+		parts []*genai.Part
+		want  []string
+	}{
+		{name: "thinking_first", parts: []*genai.Part{thought, text, call}, want: []string{"thinking", "text", "tool_use"}},
+		{name: "thinking_after_tool_use", parts: []*genai.Part{text, call, thought}, want: []string{"text", "thinking", "tool_use"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := []*genai.Content{
+				genai.NewContentFromText("Hi", "user"),
+				{Role: "model", Parts: tt.parts},
+			}
+			messages, err := converters.ContentsToMessages(contents)
+			if err != nil {
+				t.Fatalf("ContentsToMessages() error = %v", err)
+			}
+
+			var got []string
+			for _, block := range messages[1].Content {
+				switch {
+				case block.OfThinking != nil:
+					got = append(got, "thinking")
+				case block.OfText != nil:
+					got = append(got, "text")
+				case block.OfToolUse != nil:
+					got = append(got, "tool_use")
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("block types mismatch (-want +got):\n%s", diff)
+			}
+			if sig := messages[1].Content[slices.Index(got, "thinking")].OfThinking.Signature; sig != base64.StdEncoding.EncodeToString([]byte("sig1")) {
+				t.Errorf("thinking signature = %q, want the part's signature", sig)
+			}
+		})
+	}
+}
+
+// gifData encodes a 2x2 GIF with the given number of frames.
+func gifData(t *testing.T, frames int) []byte {
+	t.Helper()
+	g := &gif.GIF{}
+	for range frames {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.Black, color.White}))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("gif.EncodeAll() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// globalPaletteGIF encodes a 2x2 GIF with the given number of frames that
+// share a global color table.
+func globalPaletteGIF(t *testing.T, frames int) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	g := &gif.GIF{Config: image.Config{ColorModel: palette, Width: 2, Height: 2}}
+	for range frames {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 2, 2), palette))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("gif.EncodeAll() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestPartToContentBlock_AnimatedImages(t *testing.T) {
+	// A VP8X header with the animation flag set
+	animatedWebP := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00\x02"), make([]byte, 9)...)
+
+	tests := []struct {
+		name    string
+		blob    *genai.Blob
+		wantErr bool
+	}{
+		{name: "still_gif", blob: &genai.Blob{Data: gifData(t, 1), MIMEType: "image/gif"}},
+		{name: "animated_gif", blob: &genai.Blob{Data: gifData(t, 3), MIMEType: "image/gif"}, wantErr: true},
+		{name: "animated_gif_global_palette", blob: &genai.Blob{Data: globalPaletteGIF(t, 2), MIMEType: "image/gif"}, wantErr: true},
+		{name: "still_gif_global_palette", blob: &genai.Blob{Data: globalPaletteGIF(t, 1), MIMEType: "image/gif"}},
+		{name: "truncated_gif", blob: &genai.Blob{Data: gifData(t, 3)[:20], MIMEType: "image/gif"}},
+		{name: "animated_webp", blob: &genai.Blob{Data: animatedWebP, MIMEType: "image/webp"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := converters.PartToContentBlock(&genai.Part{InlineData: tt.blob})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "animated images are not supported") {
+					t.Errorf("PartToContentBlock() error = %v, want animated image error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("PartToContentBlock() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package converters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// JSONMIMEType is the genai ResponseMIMEType requesting JSON-only output.
+const JSONMIMEType = "application/json"
+
+// JSONPrefill is the text the assistant turn is prefilled with to make the model
+// answer with a JSON object. Anthropic has no JSON output mode, and the API omits
+// the prefill from the response, so it must be restored with [RestoreJSONPrefill].
+const JSONPrefill = "{"
+
+// JSONPrefillMessage returns the trailing assistant message that prefills the
+// model's answer with [JSONPrefill].
+func JSONPrefillMessage() anthropic.MessageParam {
+	return anthropic.NewAssistantMessage(anthropic.NewTextBlock(JSONPrefill))
+}
+
+// RestoreJSONPrefill prepends [JSONPrefill] to the first non-thought text part of
+// resp and strips trailing whitespace and code fences the model may append.
+//
+// If the model finished its turn normally, the text is validated and an error is
+// returned when it is not valid JSON. Truncated responses (for example, finish
+// reason MAX_TOKENS) are returned without validation.
+func RestoreJSONPrefill(resp *model.LLMResponse) error {
+	if resp == nil || resp.Content == nil {
+		return nil
+	}
+	for _, part := range resp.Content.Parts {
+		if part == nil || part.Thought || part.Text == "" {
+			continue
+		}
+		text := strings.TrimSpace(part.Text)
+		text = strings.TrimSpace(strings.TrimSuffix(text, "```"))
+		part.Text = JSONPrefill + text
+
+		_, stopped := resp.CustomMetadata[MetadataKeyStopSequence]
+		if resp.FinishReason == genai.FinishReasonStop && !stopped && !json.Valid([]byte(part.Text)) {
+			return fmt.Errorf("model response is not valid JSON")
+		}
+		return nil
+	}
+	return nil
+}
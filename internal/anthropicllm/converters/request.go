@@ -16,25 +16,86 @@
 package converters
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
 	"google.golang.org/genai"
 )
 
+// DocumentChunksMIMEType marks an inline data part whose Data is a JSON array of
+// strings, each a pre-chunked passage of one document. Such parts are sent as a
+// custom content document with citations enabled, so that citations reference
+// chunk indices (content_block_location) instead of character offsets. The
+// Blob's DisplayName is used as the document title.
+const DocumentChunksMIMEType = "application/vnd.adk.anthropic.chunks+json"
+
+// SearchResultMIMEType marks an inline data part whose Data is a JSON encoded
+// SearchResult. Such parts are sent as a search_result block with citations
+// enabled, so that Claude cites the retrieved passages natively
+// (search_result_location).
+const SearchResultMIMEType = "application/vnd.adk.anthropic.search-result+json"
+
+// SearchResult is the Data of a SearchResultMIMEType part: a result of a
+// search made for RAG, such as a retrieved document.
+type SearchResult struct {
+	// Source identifies where the content comes from, such as a URL.
+	Source string `json:"source"`
+	// Title is the title of the result.
+	Title string `json:"title"`
+	// Content holds the passages of the result, one text block each.
+	Content []string `json:"content"`
+}
+
+// ServerToolBlockMIMEType marks an inline data part whose Data is the JSON of a
+// content block of a tool executed by Anthropic, such as a call to a remote MCP
+// server tool (mcp_tool_use) or its result (mcp_tool_result), or a call to the
+// code execution tool (server_tool_use) or its result
+// (code_execution_tool_result). Responses report
+// such blocks as these parts rather than as function calls, which the agent
+// must not execute, and they are sent back unchanged in later requests.
+const ServerToolBlockMIMEType = "application/vnd.adk.anthropic.server-tool-block+json"
+
+// CacheBreakpointMIMEType marks an inline data part that carries no content but
+// places a prompt cache breakpoint on the system instruction block before it.
+const CacheBreakpointMIMEType = "application/vnd.adk.anthropic.cache-breakpoint"
+
+// MaxCacheBreakpoints is the maximum number of cache_control breakpoints the API
+// accepts in a single request.
+const MaxCacheBreakpoints = 4
+
+// FunctionResponsePartsKey is the genai.FunctionResponse Response key that
+// holds a list of parts (text, images, documents, search results) to send as the content
+// blocks of the tool result, instead of the JSON encoding of the response.
+// The convention applies when it is the only key in the response. The key is
+// namespaced so that tools returning data under a "parts" key are unaffected.
+const FunctionResponsePartsKey = "anthropic:parts"
+
 // ContentsToMessages converts genai Contents to Anthropic MessageParams.
 // It handles role mapping and content part conversion.
+//
+// FunctionCalls without an ID are given a synthetic ID (see assignSyntheticToolIDs),
+// and a FunctionResponse without an ID is matched to the earliest unanswered
+// FunctionCall with the same name, so hand-built histories still correlate.
+// Contents with the system role are skipped; see SystemContentsToSystem.
 func ContentsToMessages(contents []*genai.Content) ([]anthropic.MessageParam, error) {
 	if len(contents) == 0 {
 		return nil, nil
 	}
 
+	contents = assignSyntheticToolIDs(contents)
+
 	var messages []anthropic.MessageParam
 	for _, content := range contents {
-		if content == nil {
+		if content == nil || IsSystemContent(content) {
 			continue
 		}
 
@@ -53,9 +114,89 @@ func ContentsToMessages(contents []*genai.Content) ([]anthropic.MessageParam, er
 	return messages, nil
 }
 
+// assignSyntheticToolIDs returns contents in which every FunctionCall without an
+// ID has a synthetic ID of the form "toolu_adk_<content index>_<part index>".
+// The ID is derived from the call's position, so it is stable across requests
+// sharing the same history (which keeps prompt caching effective).
+//
+// A FunctionResponse without an ID receives the ID of the earliest preceding
+// FunctionCall with the same name that has not been answered yet. Responses with
+// no matching call are left unchanged and rejected later by functionResponseToBlock.
+//
+// Contents and parts that need an ID are copied; the input is never modified.
+func assignSyntheticToolIDs(contents []*genai.Content) []*genai.Content {
+	var result []*genai.Content
+	pending := make(map[string][]string) // function name -> unanswered call IDs
+
+	for ci, content := range contents {
+		if content == nil {
+			continue
+		}
+		var copied *genai.Content
+		for pi, part := range content.Parts {
+			if part == nil {
+				continue
+			}
+			var id string
+			switch {
+			case part.FunctionCall != nil && part.FunctionCall.ID == "":
+				id = fmt.Sprintf("toolu_adk_%d_%d", ci, pi)
+				pending[part.FunctionCall.Name] = append(pending[part.FunctionCall.Name], id)
+			case part.FunctionCall != nil:
+				pending[part.FunctionCall.Name] = append(pending[part.FunctionCall.Name], part.FunctionCall.ID)
+				continue
+			case part.FunctionResponse != nil && part.FunctionResponse.ID == "":
+				queue := pending[part.FunctionResponse.Name]
+				if len(queue) == 0 {
+					continue
+				}
+				id, pending[part.FunctionResponse.Name] = queue[0], queue[1:]
+			case part.FunctionResponse != nil:
+				pending[part.FunctionResponse.Name] = removeID(pending[part.FunctionResponse.Name], part.FunctionResponse.ID)
+				continue
+			default:
+				continue
+			}
+
+			if copied == nil {
+				copied = &genai.Content{Role: content.Role, Parts: slices.Clone(content.Parts)}
+				if result == nil {
+					result = slices.Clone(contents)
+				}
+				result[ci] = copied
+			}
+			p := *part
+			if p.FunctionCall != nil {
+				fc := *p.FunctionCall
+				fc.ID = id
+				p.FunctionCall = &fc
+			} else {
+				fr := *p.FunctionResponse
+				fr.ID = id
+				p.FunctionResponse = &fr
+			}
+			copied.Parts[pi] = &p
+		}
+	}
+
+	if result == nil {
+		return contents
+	}
+	return result
+}
+
+// removeID removes the first occurrence of id from ids.
+func removeID(ids []string, id string) []string {
+	if i := slices.Index(ids, id); i >= 0 {
+		return slices.Delete(ids, i, i+1)
+	}
+	return ids
+}
+
 // contentToMessage converts a single genai.Content to an Anthropic MessageParam.
+// It returns nil for contents without parts, or whose parts are all nil.
 func contentToMessage(content *genai.Content) (*anthropic.MessageParam, error) {
-	if content == nil || len(content.Parts) == 0 {
+	if content == nil || !slices.ContainsFunc(content.Parts, func(part *genai.Part) bool { return part != nil }) {
 		return nil, nil
 	}
 
@@ -107,6 +248,9 @@ func contentToMessage(content *genai.Content) (*anthropic.MessageParam, error) {
 	if len(blocks) == 0 {
 		return nil, nil
 	}
+	if role == anthropic.MessageParamRoleAssistant {
+		blocks = thinkingBeforeToolUse(blocks)
+	}
 
 	msg := anthropic.MessageParam{
 		Role:    role,
@@ -118,7 +262,8 @@ func contentToMessage(content *genai.Content) (*anthropic.MessageParam, error) {
 // mapRole maps genai role to Anthropic MessageParamRole.
 func mapRole(role string) (anthropic.MessageParamRole, error) {
 	switch strings.ToLower(role) {
-	case "user":
+	// Tool outputs are sent as tool_result blocks, which live in user turns
+	case "user", "tool":
 		return anthropic.MessageParamRoleUser, nil
 	case "model", "assistant":
 		return anthropic.MessageParamRoleAssistant, nil
@@ -127,28 +272,74 @@ func mapRole(role string) (anthropic.MessageParamRole, error) {
 	}
 }
 
+// thinkingBeforeToolUse moves thinking blocks that follow a tool_use block in
+// an assistant turn to just before the first tool_use block, since the API
+// rejects thinking that comes after the tool call it led to. The relative
+// order of the other blocks is preserved.
+func thinkingBeforeToolUse(blocks []anthropic.ContentBlockParamUnion) []anthropic.ContentBlockParamUnion {
+	first := slices.IndexFunc(blocks, func(b anthropic.ContentBlockParamUnion) bool { return b.OfToolUse != nil })
+	if first < 0 {
+		return blocks
+	}
+	isThinking := func(b anthropic.ContentBlockParamUnion) bool {
+		return b.OfThinking != nil || b.OfRedactedThinking != nil
+	}
+	if !slices.ContainsFunc(blocks[first:], isThinking) {
+		return blocks
+	}
+
+	ordered := slices.Clone(blocks[:first])
+	for _, b := range blocks[first:] {
+		if isThinking(b) {
+			ordered = append(ordered, b)
+		}
+	}
+	for _, b := range blocks[first:] {
+		if !isThinking(b) {
+			ordered = append(ordered, b)
+		}
+	}
+	return ordered
+}
+
+// ErrUnsupportedContent is wrapped by the errors returned for parts holding
+// content that Anthropic models cannot read, such as video.
+var ErrUnsupportedContent = errors.New("unsupported content")
+
 // PartToContentBlock converts a genai Part to an Anthropic ContentBlockParamUnion.
+//
+// It returns a nil block and no error for parts that are intentionally
+// skipped because they carry nothing to send: nil parts, blank text and
+// unsigned empty thoughts. Parts with content that cannot be sent, such as
+// video, return an error wrapping ErrUnsupportedContent instead of being
+// dropped silently.
 func PartToContentBlock(part *genai.Part) (*anthropic.ContentBlockParamUnion, error) {
 	if part == nil {
 		return nil, nil
 	}
+	if part.VideoMetadata != nil {
+		return nil, fmt.Errorf("%w: video input is not supported by Anthropic models", ErrUnsupportedContent)
+	}
 
-	// Text content
-	if part.Text != "" {
-		// Check if this is a thought block
-		if part.Thought {
-			// Thoughts from model responses need to be passed back with signature
-			if len(part.ThoughtSignature) > 0 {
-				block := anthropic.ContentBlockParamUnion{
-					OfThinking: &anthropic.ThinkingBlockParam{
-						Thinking:  part.Text,
-						Signature: base64.StdEncoding.EncodeToString(part.ThoughtSignature),
-					},
-				}
-				return &block, nil
-			}
-			// If no signature, treat as regular text (shouldn't happen in valid flow)
+	// Thoughts from model responses need to be passed back with signature.
+	// The API verifies it, so responses with a signature that does not
+	// decode leave ThoughtSignature empty (see decodeSignature) and the
+	// thought is sent as text below rather than failing the request.
+	if part.Thought && part.Text != "" && len(part.ThoughtSignature) > 0 {
+		block := anthropic.ContentBlockParamUnion{
+			OfThinking: &anthropic.ThinkingBlockParam{
+				Thinking:  part.Text,
+				Signature: base64.StdEncoding.EncodeToString(part.ThoughtSignature),
+			},
 		}
+		return &block, nil
+	}
+
+	// Text content. Whitespace-only text is dropped, as Anthropic rejects
+	// blank text blocks.
+	// Thoughts without a signature are sent as regular text (shouldn't happen
+	// in a valid flow).
+	if strings.TrimSpace(part.Text) != "" {
 		block := anthropic.NewTextBlock(part.Text)
 		return &block, nil
 	}
@@ -177,7 +368,7 @@ func PartToContentBlock(part *genai.Part) (*anthropic.ContentBlockParamUnion, er
 	// Executable code and CodeExecutionResult are Gemini-specific features
 	// that don't have direct Anthropic equivalents
 	if part.ExecutableCode != nil || part.CodeExecutionResult != nil {
-		return nil, fmt.Errorf("ExecutableCode and CodeExecutionResult are not supported by Anthropic")
+		return nil, fmt.Errorf("%w: ExecutableCode and CodeExecutionResult are not supported by Anthropic", ErrUnsupportedContent)
 	}
 
 	return nil, nil
@@ -189,7 +380,7 @@ func inlineDataToBlock(blob *genai.Blob) (*anthropic.ContentBlockParamUnion, err
 		return nil, nil
 	}
 
-	mimeType := strings.ToLower(blob.MIMEType)
+	mimeType := BaseMIMEType(blob.MIMEType)
 
 	// Handle images
 	if strings.HasPrefix(mimeType, "image/") {
@@ -197,6 +388,9 @@ func inlineDataToBlock(blob *genai.Blob) (*anthropic.ContentBlockParamUnion, err
 		if err != nil {
 			return nil, err
 		}
+		if isAnimated(mimeType, blob.Data) {
+			return nil, fmt.Errorf("animated images are not supported (%s): Claude only reads still images; send a single frame instead", mimeType)
+		}
 		block := anthropic.ContentBlockParamUnion{
 			OfImage: &anthropic.ImageBlockParam{
 				Source: anthropic.ImageBlockParamSourceUnion{
@@ -224,7 +418,148 @@ func inlineDataToBlock(blob *genai.Blob) (*anthropic.ContentBlockParamUnion, err
 		return &block, nil
 	}
 
-	return nil, fmt.Errorf("unsupported MIME type for inline data: %s", mimeType)
+	// Handle pre-chunked custom content documents
+	if mimeType == DocumentChunksMIMEType {
+		return documentChunksToBlock(blob)
+	}
+
+	// Handle search results supplied for RAG
+	if mimeType == SearchResultMIMEType {
+		return searchResultToBlock(blob)
+	}
+
+	// Handle blocks of tools executed by Anthropic, which are sent back as is
+	if mimeType == ServerToolBlockMIMEType {
+		if !json.Valid(blob.Data) {
+			return nil, fmt.Errorf("invalid server tool block: %s", blob.Data)
+		}
+		block := param.Override[anthropic.ContentBlockParamUnion](json.RawMessage(blob.Data))
+		return &block, nil
+	}
+
+	// Handle plain text documents, with citations enabled so the model can
+	// cite passages from them.
+	if mimeType == "text/plain" {
+		doc := &anthropic.DocumentBlockParam{
+			Source: anthropic.DocumentBlockParamSourceUnion{
+				OfText: &anthropic.PlainTextSourceParam{
+					Data: string(blob.Data),
+				},
+			},
+			Citations: anthropic.CitationsConfigParam{Enabled: anthropic.Bool(true)},
+		}
+		if blob.DisplayName != "" {
+			doc.Title = anthropic.String(blob.DisplayName)
+		}
+		block := anthropic.ContentBlockParamUnion{OfDocument: doc}
+		return &block, nil
+	}
+
+	return nil, unsupportedMIMEType("inline data", mimeType)
+}
+
+// documentChunksToBlock converts a DocumentChunksMIMEType blob to a custom
+// content document block with one text block per chunk.
+func documentChunksToBlock(blob *genai.Blob) (*anthropic.ContentBlockParamUnion, error) {
+	var chunks []string
+	if err := json.Unmarshal(blob.Data, &chunks); err != nil {
+		return nil, fmt.Errorf("invalid document chunks: data must be a JSON array of strings: %w", err)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("invalid document chunks: at least one chunk is required")
+	}
+
+	items := make([]anthropic.ContentBlockSourceContentItemUnionParam, 0, len(chunks))
+	for _, chunk := range chunks {
+		items = append(items, anthropic.ContentBlockSourceContentItemUnionParam{
+			OfText: &anthropic.TextBlockParam{Text: chunk},
+		})
+	}
+
+	doc := &anthropic.DocumentBlockParam{
+		Source: anthropic.DocumentBlockParamSourceUnion{
+			OfContent: &anthropic.ContentBlockSourceParam{
+				Content: anthropic.ContentBlockSourceContentUnionParam{
+					OfContentBlockSourceContent: items,
+				},
+			},
+		},
+		Citations: anthropic.CitationsConfigParam{Enabled: anthropic.Bool(true)},
+	}
+	if blob.DisplayName != "" {
+		doc.Title = anthropic.String(blob.DisplayName)
+	}
+	block := anthropic.ContentBlockParamUnion{OfDocument: doc}
+	return &block, nil
+}
+
+// searchResultToBlock converts a SearchResultMIMEType blob to a search_result
+// block with one text block per passage.
+func searchResultToBlock(blob *genai.Blob) (*anthropic.ContentBlockParamUnion, error) {
+	var result SearchResult
+	if err := json.Unmarshal(blob.Data, &result); err != nil {
+		return nil, fmt.Errorf("invalid search result: data must be a JSON object with source, title and content: %w", err)
+	}
+	if result.Source == "" || result.Title == "" {
+		return nil, fmt.Errorf("invalid search result: source and title are required")
+	}
+	if len(result.Content) == 0 {
+		return nil, fmt.Errorf("invalid search result: at least one passage is required")
+	}
+
+	content := make([]anthropic.TextBlockParam, 0, len(result.Content))
+	for _, passage := range result.Content {
+		content = append(content, anthropic.TextBlockParam{Text: passage})
+	}
+	block := anthropic.ContentBlockParamUnion{OfSearchResult: &anthropic.SearchResultBlockParam{
+		Source:    result.Source,
+		Title:     result.Title,
+		Content:   content,
+		Citations: anthropic.CitationsConfigParam{Enabled: anthropic.Bool(true)},
+	}}
+	return &block, nil
+}
+
+// parseDataURI decodes an RFC 2397 data URI ("data:[<mediatype>][;base64],<data>")
+// into a Blob. The media type, including any parameters such as charset, is
+// taken from the URI, falling back to fallbackMIMEType when the URI has none.
+func parseDataURI(uri, fallbackMIMEType string) (*genai.Blob, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid data URI: missing ',' separator")
+	}
+
+	mimeType, isBase64 := header, false
+	if trimmed, found := strings.CutSuffix(header, ";base64"); found {
+		mimeType, isBase64 = trimmed, true
+	}
+	if BaseMIMEType(mimeType) == "" {
+		mimeType = fallbackMIMEType
+	}
+
+	var data []byte
+	if isBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 payload in data URI: %w", err)
+		}
+		data = decoded
+	} else {
+		decoded, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload in data URI: %w", err)
+		}
+		data = []byte(decoded)
+	}
+
+	return &genai.Blob{MIMEType: mimeType, Data: data}, nil
+}
+
+// BaseMIMEType returns the lowercased media type without parameters,
+// e.g. "text/plain" for "text/plain; charset=utf-8".
+func BaseMIMEType(mimeType string) string {
+	base, _, _ := strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
 }
 
 // mapImageMediaType maps MIME types to Anthropic Base64ImageSourceMediaType.
@@ -243,13 +578,95 @@ func mapImageMediaType(mimeType string) (anthropic.Base64ImageSourceMediaType, e
 	}
 }
 
+// isAnimated reports whether data is a GIF with more than one frame or an
+// animated WebP image.
+func isAnimated(mimeType string, data []byte) bool {
+	switch mimeType {
+	case "image/gif":
+		return gifFrameCount(data) > 1
+	case "image/webp":
+		// Animated WebP files use the extended (VP8X) format with the
+		// animation flag set.
+		return len(data) > 20 && string(data[0:4]) == "RIFF" && string(data[8:16]) == "WEBPVP8X" && data[20]&0x02 != 0
+	default:
+		return false
+	}
+}
+
+// gifFrameCount returns the number of image descriptors in the GIF data, up
+// to two, by walking its blocks without decoding any pixels. It stops at the
+// first malformed or truncated block.
+func gifFrameCount(data []byte) int {
+	// Header and logical screen descriptor, then the global color table.
+	if len(data) < 13 || string(data[:3]) != "GIF" {
+		return 0
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks returns the position after the data sub-blocks at i, or -1.
+	skipSubBlocks := func(i int) int {
+		for i < len(data) {
+			size := int(data[i])
+			i++
+			if size == 0 {
+				return i
+			}
+			i += size
+		}
+		return -1
+	}
+
+	frames := 0
+	for pos < len(data) && frames < 2 {
+		switch data[pos] {
+		case 0x21: // extension: label, then sub-blocks
+			pos = skipSubBlocks(pos + 2)
+		case 0x2C: // image descriptor, local color table, LZW code size, sub-blocks
+			if pos+10 > len(data) {
+				return frames
+			}
+			frames++
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos = skipSubBlocks(pos + 1)
+		default: // trailer or garbage
+			return frames
+		}
+		if pos < 0 {
+			return frames
+		}
+	}
+	return frames
+}
+
 // fileDataToBlock converts URI-based file data to an Anthropic content block.
 func fileDataToBlock(fileData *genai.FileData) (*anthropic.ContentBlockParamUnion, error) {
 	if fileData == nil {
 		return nil, nil
 	}
 
-	mimeType := strings.ToLower(fileData.MIMEType)
+	// Anthropic does not accept data URIs as URL sources, so send them inline
+	if strings.HasPrefix(fileData.FileURI, "data:") {
+		blob, err := parseDataURI(fileData.FileURI, fileData.MIMEType)
+		if err != nil {
+			return nil, err
+		}
+		return inlineDataToBlock(blob)
+	}
+
+	// Claude fetches URL sources itself, over HTTP(S), on both the Anthropic
+	// API and Vertex AI; neither has a Cloud Storage source type.
+	if strings.HasPrefix(fileData.FileURI, "gs://") {
+		return nil, fmt.Errorf("gs:// URIs are not supported (%s): Claude cannot read from Cloud Storage; provide the data inline or use a signed HTTPS URL", fileData.FileURI)
+	}
+
+	mimeType := BaseMIMEType(fileData.MIMEType)
 
 	// Handle images via URL
 	if strings.HasPrefix(mimeType, "image/") {
@@ -279,7 +696,26 @@ func fileDataToBlock(fileData *genai.FileData) (*anthropic.ContentBlockParamUnio
 		return &block, nil
 	}
 
-	return nil, fmt.Errorf("unsupported MIME type for file data: %s", mimeType)
+	// Anthropic only accepts plain text documents inline
+	if mimeType == "text/plain" {
+		return nil, fmt.Errorf("text/plain documents cannot be referenced by URL (%s); provide them as inline data", fileData.FileURI)
+	}
+
+	return nil, unsupportedMIMEType("file data", mimeType)
+}
+
+// unsupportedMIMEType returns the error for source (inline or file data) of a
+// MIME type that cannot be converted.
+func unsupportedMIMEType(source, mimeType string) error {
+	switch {
+	case strings.HasPrefix(mimeType, "video/"):
+		return fmt.Errorf("%w: video input is not supported by Anthropic models (%s)", ErrUnsupportedContent, mimeType)
+	case strings.HasPrefix(mimeType, "audio/"):
+		// The Messages API has no audio content block yet; once it does,
+		// audio should be converted like images instead.
+		return fmt.Errorf("%w: audio input is not supported by this model (%s); transcribe it to text first", ErrUnsupportedContent, mimeType)
+	}
+	return fmt.Errorf("%w: unsupported MIME type for %s: %s", ErrUnsupportedContent, source, mimeType)
 }
 
 // functionResponseToBlock converts a FunctionResponse to an Anthropic tool result block.
@@ -294,6 +730,17 @@ func functionResponseToBlock(resp *genai.FunctionResponse) (*anthropic.ContentBl
 		return nil, fmt.Errorf("FunctionResponse.ID is required for tool call correlation (function: %s)", resp.Name)
 	}
 
+	if parts, ok := functionResponseParts(resp.Response); ok {
+		content, err := partsToToolResultContent(parts)
+		if err != nil {
+			return nil, fmt.Errorf("function response %s: %w", resp.Name, err)
+		}
+		return &anthropic.ContentBlockParamUnion{OfToolResult: &anthropic.ToolResultBlockParam{
+			ToolUseID: resp.ID,
+			Content:   content,
+		}}, nil
+	}
+
 	// Convert the response to JSON string
 	var content string
 	if resp.Response != nil {
@@ -308,6 +755,82 @@ func functionResponseToBlock(resp *genai.FunctionResponse) (*anthropic.ContentBl
 	return &block, nil
 }
 
+// functionResponseParts returns the parts of a response that follows the
+// FunctionResponsePartsKey convention: a single key holding a list of parts,
+// either as []*genai.Part or, after a JSON round trip, as a list of objects.
+// A list holding an object that is not a part with content is not a list of
+// parts, and the response is then sent as JSON so that no data is lost.
+func functionResponseParts(response map[string]any) ([]*genai.Part, bool) {
+	if len(response) != 1 {
+		return nil, false
+	}
+	switch v := response[FunctionResponsePartsKey].(type) {
+	case []*genai.Part:
+		return v, true
+	case []any:
+		parts := make([]*genai.Part, 0, len(v))
+		for _, item := range v {
+			if part, ok := item.(*genai.Part); ok {
+				parts = append(parts, part)
+				continue
+			}
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			part, ok := decodePart(obj)
+			if !ok {
+				return nil, false
+			}
+			parts = append(parts, part)
+		}
+		return parts, true
+	default:
+		return nil, false
+	}
+}
+
+// decodePart decodes a part from its JSON object. It reports false if the
+// object has fields that parts do not have, or no content.
+func decodePart(obj map[string]any) (*genai.Part, bool) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var part genai.Part
+	if err := dec.Decode(&part); err != nil || reflect.ValueOf(part).IsZero() {
+		return nil, false
+	}
+	return &part, true
+}
+
+// partsToToolResultContent converts parts to the content blocks of a tool result.
+func partsToToolResultContent(parts []*genai.Part) ([]anthropic.ToolResultBlockParamContentUnion, error) {
+	var content []anthropic.ToolResultBlockParamContentUnion
+	for _, part := range parts {
+		block, err := PartToContentBlock(part)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case block == nil:
+		case block.OfText != nil:
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfText: block.OfText})
+		case block.OfImage != nil:
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfImage: block.OfImage})
+		case block.OfDocument != nil:
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfDocument: block.OfDocument})
+		case block.OfSearchResult != nil:
+			content = append(content, anthropic.ToolResultBlockParamContentUnion{OfSearchResult: block.OfSearchResult})
+		default:
+			return nil, fmt.Errorf("tool results can only contain text, images, documents and search results")
+		}
+	}
+	return content, nil
+}
+
 // functionCallToBlock converts a FunctionCall to an Anthropic tool use block.
 // This is used when passing model responses back (e.g., in conversation history).
 func functionCallToBlock(call *genai.FunctionCall) (*anthropic.ContentBlockParamUnion, error) {
@@ -326,6 +849,7 @@ func functionCallToBlock(call *genai.FunctionCall) (*anthropic.ContentBlockParam
 }
 
 // SystemInstructionToSystem converts a genai SystemInstruction to Anthropic system text blocks.
+// A CacheBreakpointMIMEType part marks the preceding text block as cacheable.
 func SystemInstructionToSystem(instruction *genai.Content) []anthropic.TextBlockParam {
 	if instruction == nil || len(instruction.Parts) == 0 {
 		return nil
@@ -333,6 +857,12 @@ func SystemInstructionToSystem(instruction *genai.Content) []anthropic.TextBlock
 
 	var blocks []anthropic.TextBlockParam
 	for _, part := range instruction.Parts {
+		if isCacheBreakpoint(part) {
+			if len(blocks) > 0 {
+				blocks[len(blocks)-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
+			}
+			continue
+		}
 		if part != nil && part.Text != "" {
 			blocks = append(blocks, anthropic.TextBlockParam{
 				Text: part.Text,
@@ -342,6 +872,29 @@ func SystemInstructionToSystem(instruction *genai.Content) []anthropic.TextBlock
 	return blocks
 }
 
+// SystemContentsToSystem converts the contents with the system role to
+// Anthropic system text blocks, in order, for system prompts composed from
+// several contents. Other contents are ignored.
+func SystemContentsToSystem(contents []*genai.Content) []anthropic.TextBlockParam {
+	var blocks []anthropic.TextBlockParam
+	for _, content := range contents {
+		if IsSystemContent(content) {
+			blocks = append(blocks, SystemInstructionToSystem(content)...)
+		}
+	}
+	return blocks
+}
+
+// IsSystemContent reports whether content has the system role, in any case.
+func IsSystemContent(content *genai.Content) bool {
+	return content != nil && strings.EqualFold(content.Role, "system")
+}
+
+// isCacheBreakpoint reports whether part is a CacheBreakpointMIMEType marker.
+func isCacheBreakpoint(part *genai.Part) bool {
+	return part != nil && part.InlineData != nil && part.InlineData.MIMEType == CacheBreakpointMIMEType
+}
+
 // mergeConsecutiveMessages merges consecutive messages with the same role.
 // Anthropic requires strictly alternating user/assistant messages.
 func mergeConsecutiveMessages(messages []anthropic.MessageParam) []anthropic.MessageParam {
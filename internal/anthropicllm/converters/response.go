@@ -18,14 +18,57 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/respjson"
 	"google.golang.org/genai"
 
 	"google.golang.org/adk/model"
 )
 
+// FinishReasonPauseTurn is reported when Anthropic pauses a long-running turn
+// (stop_reason "pause_turn"), typically during server tool execution.
+// The caller should resend the request, including the paused response, to let
+// the model continue the turn.
+const FinishReasonPauseTurn genai.FinishReason = "PAUSE_TURN"
+
+// RawToolInputKey is the FunctionCall.Args key holding the raw tool input when
+// it could not be decoded as a JSON object. Responses containing such a call
+// report genai.FinishReasonMalformedFunctionCall.
+const RawToolInputKey = "__raw__"
+
+// Keys used in model.LLMResponse.CustomMetadata for Anthropic-specific response data.
+const (
+	// MetadataKeyStopSequence holds the stop sequence (string) that ended generation.
+	MetadataKeyStopSequence = "anthropic:stop_sequence"
+	// MetadataKeyServiceTier holds the service tier (string) that served the request.
+	MetadataKeyServiceTier = "anthropic:service_tier"
+	// MetadataKeyWebSearchRequests holds the number of server-side web searches
+	// (int64) performed while generating the response.
+	MetadataKeyWebSearchRequests = "anthropic:web_search_requests"
+	// MetadataKeyModel holds the name of the model (string) that served the request.
+	MetadataKeyModel = "anthropic:model"
+	// MetadataKeyTruncatedToolCall holds the ID (string) of the function call
+	// that was cut off by the max_tokens limit. Its Args hold the partial
+	// input under RawToolInputKey rather than arguments that may be
+	// incomplete; retry with a larger maximum number of output tokens.
+	MetadataKeyTruncatedToolCall = "anthropic:truncated_tool_call"
+	// MetadataKeyRequestedModel holds the name of the model (string) the
+	// request asked for. It is only set when the model that served the
+	// request is a different one.
+	MetadataKeyRequestedModel = "anthropic:requested_model"
+	// MetadataKeyContainer holds the ID (string) of the code execution
+	// container used by the request. It is only set when one was used.
+	MetadataKeyContainer = "anthropic:container"
+	// MetadataKeyCacheCreationTokens holds the number of input tokens (int64)
+	// written to the prompt cache. It is only set when some were.
+	MetadataKeyCacheCreationTokens = "anthropic:cache_creation_tokens"
+)
+
 // MessageToLLMResponse converts an Anthropic Message to a model.LLMResponse.
+// A Message is a complete turn, so the response has TurnComplete set.
 func MessageToLLMResponse(msg *anthropic.Message) (*model.LLMResponse, error) {
 	if msg == nil {
 		return nil, fmt.Errorf("nil message received")
@@ -37,13 +80,27 @@ func MessageToLLMResponse(msg *anthropic.Message) (*model.LLMResponse, error) {
 	}
 
 	var allCitations []*genai.Citation
-	for _, block := range msg.Content {
+	malformedCall := false
+	truncated := truncatedToolUse(msg)
+	for i, block := range msg.Content {
+		// Empty text blocks, which may precede a tool call, carry nothing
+		if block.Type == "text" && block.Text == "" && len(block.Citations) == 0 {
+			continue
+		}
 		part, err := ContentBlockToGenaiPart(block)
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert content block: %w", err)
 		}
 		if part != nil {
 			content.Parts = append(content.Parts, part)
+			if part.FunctionCall != nil {
+				if i == truncated {
+					// Never pass on arguments that may be incomplete
+					part.FunctionCall.Args = map[string]any{RawToolInputKey: rawToolInput(block.Input)}
+				} else if _, ok := part.FunctionCall.Args[RawToolInputKey]; ok {
+					malformedCall = true
+				}
+			}
 		}
 		// Collect citations from text blocks
 		if textBlock, ok := block.AsAny().(anthropic.TextBlock); ok {
@@ -57,15 +114,202 @@ func MessageToLLMResponse(msg *anthropic.Message) (*model.LLMResponse, error) {
 		Content:       content,
 		UsageMetadata: UsageToMetadata(msg.Usage),
 		FinishReason:  StopReasonToFinishReason(msg.StopReason),
+		TurnComplete:  true,
 	}
 
 	if len(allCitations) > 0 {
 		resp.CitationMetadata = &genai.CitationMetadata{Citations: allCitations}
 	}
 
+	if malformedCall {
+		resp.FinishReason = genai.FinishReasonMalformedFunctionCall
+	}
+
+	// As in genai, CandidatesTokenCount excludes the thinking tokens
+	resp.UsageMetadata.ThoughtsTokenCount = estimateThinkingTokens(msg)
+	resp.UsageMetadata.CandidatesTokenCount -= resp.UsageMetadata.ThoughtsTokenCount
+
+	if truncated >= 0 {
+		setCustomMetadata(resp, MetadataKeyTruncatedToolCall, msg.Content[truncated].ID)
+	}
+	if msg.Model != "" {
+		setCustomMetadata(resp, MetadataKeyModel, string(msg.Model))
+	}
+	if msg.StopSequence != "" {
+		setCustomMetadata(resp, MetadataKeyStopSequence, msg.StopSequence)
+	}
+	if id := containerID(msg); id != "" {
+		setCustomMetadata(resp, MetadataKeyContainer, id)
+	}
+	if msg.Usage.ServiceTier != "" {
+		setCustomMetadata(resp, MetadataKeyServiceTier, string(msg.Usage.ServiceTier))
+	}
+	if n := msg.Usage.ServerToolUse.WebSearchRequests; n > 0 {
+		setCustomMetadata(resp, MetadataKeyWebSearchRequests, n)
+	}
+	if n := msg.Usage.CacheCreationInputTokens; n > 0 {
+		setCustomMetadata(resp, MetadataKeyCacheCreationTokens, n)
+	}
+
 	return resp, nil
 }
 
+// containerID returns the ID of the code execution container of msg, which
+// the SDK does not model, or an empty string.
+func containerID(msg *anthropic.Message) string {
+	field, ok := msg.JSON.ExtraFields["container"]
+	if !ok {
+		return ""
+	}
+	var container struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal([]byte(field.Raw()), &container) != nil {
+		return ""
+	}
+	return container.ID
+}
+
+// AccumulateContainer copies the code execution container reported by a
+// message_delta event to msg, which is being accumulated from a stream, since
+// the SDK does not model it and Accumulate drops it.
+func AccumulateContainer(msg *anthropic.Message, event anthropic.MessageDeltaEvent) {
+	field, ok := event.Delta.JSON.ExtraFields["container"]
+	if !ok || field.Raw() == "null" {
+		return
+	}
+	if msg.JSON.ExtraFields == nil {
+		msg.JSON.ExtraFields = make(map[string]respjson.Field)
+	}
+	msg.JSON.ExtraFields["container"] = field
+}
+
+// QuoteInvalidToolInput replaces the input of the tool_use block at index in
+// msg, which is being accumulated from a stream, by a JSON string holding it if
+// it is not valid JSON, as when the response is cut off in the middle of a
+// tool call. Call it before accumulating the content_block_stop event of the
+// block, which fails to encode invalid JSON.
+func QuoteInvalidToolInput(msg *anthropic.Message, index int64) {
+	if index < 0 || index >= int64(len(msg.Content)) {
+		return
+	}
+	block := &msg.Content[index]
+	if block.Type != "tool_use" || json.Valid(block.Input) {
+		return
+	}
+	block.Input, _ = json.Marshal(string(block.Input))
+}
+
+// rawToolInput returns the raw tool input, unquoting the input of a block
+// passed through QuoteInvalidToolInput. Tool inputs are objects, so a string
+// can only come from there.
+func rawToolInput(input json.RawMessage) string {
+	var quoted string
+	if json.Unmarshal(input, &quoted) == nil {
+		return quoted
+	}
+	return string(input)
+}
+
+// truncatedToolUse returns the index of the tool_use block of msg that was cut
+// off by the max_tokens limit, or -1. Only the last block of a message can be.
+func truncatedToolUse(msg *anthropic.Message) int {
+	last := len(msg.Content) - 1
+	if msg.StopReason != anthropic.StopReasonMaxTokens || last < 0 || msg.Content[last].Type != "tool_use" {
+		return -1
+	}
+	return last
+}
+
+// charsPerToken approximates the number of characters in a token of English
+// text, for estimates where no token count is available.
+const charsPerToken = 4
+
+// estimateThinkingTokens estimates how many of the output tokens of msg were
+// spent on thinking. The API only reports the total number of output tokens,
+// so the estimate is based on the length of the thinking text, and is at most
+// the number of output tokens. Redacted thinking is not counted.
+func estimateThinkingTokens(msg *anthropic.Message) int32 {
+	chars := 0
+	for _, block := range msg.Content {
+		if block.Type == "thinking" {
+			chars += utf8.RuneCountInString(block.Thinking)
+		}
+	}
+	tokens := (int64(chars) + charsPerToken - 1) / charsPerToken
+	return int32(min(tokens, msg.Usage.OutputTokens))
+}
+
+// CheckServedModel reports whether the model that served resp, as recorded
+// under MetadataKeyModel, is not the requested model, and if so records the
+// requested name under MetadataKeyRequestedModel. An alias such as
+// "claude-sonnet-4-5", "claude-sonnet-4-0" or "claude-3-5-haiku-latest"
+// matches the dated versions it resolves to, that is the alias followed by a
+// "-YYYYMMDD" date, and Vertex AI names match with "@" in place of "-".
+func CheckServedModel(resp *model.LLMResponse, requested string) bool {
+	served, _ := resp.CustomMetadata[MetadataKeyModel].(string)
+	if served == "" || requested == "" {
+		return false
+	}
+	alias := strings.ReplaceAll(requested, "@", "-")
+	alias = strings.TrimSuffix(strings.TrimSuffix(alias, "-latest"), "-0")
+	if served == alias || strings.HasPrefix(served, alias) && isDateSuffix(served[len(alias):]) {
+		return false
+	}
+	setCustomMetadata(resp, MetadataKeyRequestedModel, requested)
+	return true
+}
+
+// isDateSuffix reports whether s is the "-YYYYMMDD" suffix of a dated model
+// version.
+func isDateSuffix(s string) bool {
+	if len(s) != 9 || s[0] != '-' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MergeTextParts concatenates runs of adjacent text parts in content into a
+// single part. Thoughts and other parts are kept as they are and separate the
+// runs.
+func MergeTextParts(content *genai.Content) {
+	if content == nil {
+		return
+	}
+	merged := content.Parts[:0]
+	for _, part := range content.Parts {
+		if isPlainText(part) && len(merged) > 0 && isPlainText(merged[len(merged)-1]) {
+			prev := *merged[len(merged)-1]
+			prev.Text += part.Text
+			merged[len(merged)-1] = &prev
+			continue
+		}
+		merged = append(merged, part)
+	}
+	clear(content.Parts[len(merged):])
+	content.Parts = merged
+}
+
+// isPlainText reports whether part holds text only.
+func isPlainText(part *genai.Part) bool {
+	return part != nil && !part.Thought && part.FunctionCall == nil && part.FunctionResponse == nil &&
+		part.InlineData == nil && part.FileData == nil && part.ExecutableCode == nil &&
+		part.CodeExecutionResult == nil
+}
+
+// setCustomMetadata sets a CustomMetadata entry, allocating the map if needed.
+func setCustomMetadata(resp *model.LLMResponse, key string, value any) {
+	if resp.CustomMetadata == nil {
+		resp.CustomMetadata = make(map[string]any)
+	}
+	resp.CustomMetadata[key] = value
+}
+
 // ContentBlockToGenaiPart converts an Anthropic ContentBlockUnion to a genai.Part.
 func ContentBlockToGenaiPart(block anthropic.ContentBlockUnion) (*genai.Part, error) {
 	switch variant := block.AsAny().(type) {
@@ -74,11 +318,10 @@ func ContentBlockToGenaiPart(block anthropic.ContentBlockUnion) (*genai.Part, er
 
 	case anthropic.ThinkingBlock:
 		// Map thinking blocks to genai.Part with Thought=true
-		signature, _ := base64.StdEncoding.DecodeString(variant.Signature)
 		return &genai.Part{
 			Text:             variant.Thinking,
 			Thought:          true,
-			ThoughtSignature: signature,
+			ThoughtSignature: decodeSignature(variant.Signature),
 		}, nil
 
 	case anthropic.RedactedThinkingBlock:
@@ -92,9 +335,10 @@ func ContentBlockToGenaiPart(block anthropic.ContentBlockUnion) (*genai.Part, er
 		// Convert to FunctionCall
 		args := make(map[string]any)
 		if variant.Input != nil {
-			// Input is json.RawMessage, unmarshal it
+			// Input is json.RawMessage, unmarshal it. If it is not a JSON object,
+			// keep the raw input rather than calling the tool with no arguments.
 			if err := json.Unmarshal(variant.Input, &args); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tool input for %q (id=%s): %w", variant.Name, variant.ID, err)
+				args = map[string]any{RawToolInputKey: string(variant.Input)}
 			}
 		}
 		return &genai.Part{
@@ -106,6 +350,9 @@ func ContentBlockToGenaiPart(block anthropic.ContentBlockUnion) (*genai.Part, er
 		}, nil
 
 	case anthropic.ServerToolUseBlock:
+		if isServerToolBlock(block) {
+			return serverToolBlockPart(block), nil
+		}
 		// Server-side tool use (web search, etc.)
 		args := make(map[string]any)
 		if variant.Input != nil {
@@ -130,10 +377,69 @@ func ContentBlockToGenaiPart(block anthropic.ContentBlockUnion) (*genai.Part, er
 		// Web search results from Anthropic's built-in web search tool
 		return webSearchResultToFunctionResponse(variant), nil
 
-	default:
-		// Unknown block type - skip
-		return nil, nil
 	}
+
+	// Blocks from beta features are not modeled by the SDK's union. Calls
+	// to remote MCP servers and to the code execution tool, and their
+	// results, are executed by Anthropic, so they are passed through rather
+	// than reported as function calls that the agent would try to execute.
+	if isServerToolBlock(block) {
+		return serverToolBlockPart(block), nil
+	}
+	// Unknown block type - skip
+	return nil, nil
+}
+
+// codeExecutionToolName is the name of the code execution tool in
+// server_tool_use blocks.
+const codeExecutionToolName = "code_execution"
+
+// isServerToolBlock reports whether block is passed through as a
+// ServerToolBlockMIMEType part.
+func isServerToolBlock(block anthropic.ContentBlockUnion) bool {
+	switch block.Type {
+	case "mcp_tool_use", "mcp_tool_result", "code_execution_tool_result":
+		return true
+	case "server_tool_use":
+		return block.Name == codeExecutionToolName
+	}
+	return false
+}
+
+// serverToolBlockPart returns a ServerToolBlockMIMEType part holding block.
+func serverToolBlockPart(block anthropic.ContentBlockUnion) *genai.Part {
+	return &genai.Part{InlineData: &genai.Blob{
+		MIMEType: ServerToolBlockMIMEType,
+		Data:     []byte(block.RawJSON()),
+	}}
+}
+
+// RestoreServerToolBlock restores the content block started by start in msg,
+// which is being accumulated from a stream, if it is passed through as a
+// ServerToolBlockMIMEType part. Accumulate re-encodes blocks on
+// content_block_stop from the fields the SDK models, which drops fields of
+// beta blocks such as server_name. Call it after accumulating that event.
+func RestoreServerToolBlock(msg *anthropic.Message, start anthropic.ContentBlockStartEvent) {
+	if start.Index < 0 || start.Index >= int64(len(msg.Content)) {
+		return
+	}
+	block := &msg.Content[start.Index]
+	if !isServerToolBlock(*block) {
+		return
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal([]byte(start.ContentBlock.RawJSON()), &fields) != nil {
+		return
+	}
+	// Tool input is streamed as deltas after the start event
+	if len(block.Input) > 0 {
+		fields["input"] = block.Input
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+	_ = block.UnmarshalJSON(data)
 }
 
 // webSearchResultToFunctionResponse converts a WebSearchToolResultBlock to a FunctionResponse Part.
@@ -181,11 +487,19 @@ func textCitationsToSlice(citations []anthropic.TextCitationUnion) []*genai.Cita
 		case "char_location":
 			citation.StartIndex = int32(c.StartCharIndex)
 			citation.EndIndex = int32(c.EndCharIndex)
+		case "content_block_location":
+			// Indices refer to chunks of a custom content document
+			citation.StartIndex = int32(c.StartBlockIndex)
+			citation.EndIndex = int32(c.EndBlockIndex)
 		case "web_search_result_location":
 			citation.Title = c.Title
 			citation.URI = c.URL
 		case "search_result_location":
+			// Indices refer to the passages of a search result
 			citation.Title = c.Title
+			citation.URI = c.Source
+			citation.StartIndex = int32(c.StartBlockIndex)
+			citation.EndIndex = int32(c.EndBlockIndex)
 		}
 
 		result = append(result, citation)
@@ -195,14 +509,25 @@ func textCitationsToSlice(citations []anthropic.TextCitationUnion) []*genai.Cita
 }
 
 // UsageToMetadata converts Anthropic Usage to genai UsageMetadata.
+// As in genai, PromptTokenCount counts all input tokens: uncached ones, and
+// those read from or written to the prompt cache. Tokens read from the cache
+// are also reported as CachedContentTokenCount.
 func UsageToMetadata(usage anthropic.Usage) *genai.GenerateContentResponseUsageMetadata {
+	prompt := promptTokens(usage)
 	return &genai.GenerateContentResponseUsageMetadata{
-		PromptTokenCount:     int32(usage.InputTokens),
-		CandidatesTokenCount: int32(usage.OutputTokens),
-		TotalTokenCount:      int32(usage.InputTokens + usage.OutputTokens),
+		PromptTokenCount:        int32(prompt),
+		CandidatesTokenCount:    int32(usage.OutputTokens),
+		TotalTokenCount:         int32(prompt + usage.OutputTokens),
+		CachedContentTokenCount: int32(usage.CacheReadInputTokens),
 	}
 }
 
+// promptTokens returns the number of input tokens of usage, whether cached or
+// not. Anthropic reports uncached input tokens only as InputTokens.
+func promptTokens(usage anthropic.Usage) int64 {
+	return usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens
+}
+
 // StopReasonToFinishReason maps Anthropic StopReason to genai FinishReason.
 func StopReasonToFinishReason(sr anthropic.StopReason) genai.FinishReason {
 	switch sr {
@@ -214,6 +539,10 @@ func StopReasonToFinishReason(sr anthropic.StopReason) genai.FinishReason {
 		return genai.FinishReasonStop
 	case anthropic.StopReasonToolUse:
 		return genai.FinishReasonStop
+	case anthropic.StopReasonPauseTurn:
+		return FinishReasonPauseTurn
+	case anthropic.StopReasonRefusal:
+		return genai.FinishReasonSafety
 	default:
 		return genai.FinishReasonUnspecified
 	}
@@ -233,6 +562,46 @@ func StreamDeltaToPartialResponse(text string) *model.LLMResponse {
 	}
 }
 
+// StreamMessageStartToPartialResponse converts the usage reported by a message_start
+// event to a partial LLMResponse carrying only the prompt token count.
+// The final response remains authoritative for token totals.
+func StreamMessageStartToPartialResponse(usage anthropic.Usage) *model.LLMResponse {
+	return &model.LLMResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:        int32(promptTokens(usage)),
+			CachedContentTokenCount: int32(usage.CacheReadInputTokens),
+		},
+		Partial: true,
+	}
+}
+
+// StreamStopReasonToPartialResponse converts the stop reason reported by a
+// message_delta event to a partial LLMResponse carrying only the finish reason,
+// so that callers learn how generation ended (for example, truncation at
+// max_tokens) before the final response.
+func StreamStopReasonToPartialResponse(sr anthropic.StopReason) *model.LLMResponse {
+	return &model.LLMResponse{
+		FinishReason: StopReasonToFinishReason(sr),
+		Partial:      true,
+	}
+}
+
+// StreamCitationToPartialResponse converts a streamed citation, which applies to
+// the text block being streamed, to a partial LLMResponse carrying only the
+// citation. The final response holds all the citations of the message.
+func StreamCitationToPartialResponse(citation anthropic.CitationsDeltaCitationUnion) *model.LLMResponse {
+	var c anthropic.TextCitationUnion
+	if err := json.Unmarshal([]byte(citation.RawJSON()), &c); err != nil {
+		return nil
+	}
+	return &model.LLMResponse{
+		CitationMetadata: &genai.CitationMetadata{
+			Citations: textCitationsToSlice([]anthropic.TextCitationUnion{c}),
+		},
+		Partial: true,
+	}
+}
+
 // StreamThinkingDeltaToPartialResponse converts a streaming thinking delta to a partial LLMResponse.
 func StreamThinkingDeltaToPartialResponse(thinking string) *model.LLMResponse {
 	return &model.LLMResponse{
@@ -248,3 +617,24 @@ func StreamThinkingDeltaToPartialResponse(thinking string) *model.LLMResponse {
 		Partial: true,
 	}
 }
+
+// StreamThinkingSignatureToPartialResponse converts the signature that closes a
+// streamed thinking block to a partial LLMResponse. Any thinking text not yet
+// emitted is included with the signature.
+func StreamThinkingSignatureToPartialResponse(thinking, signature string) *model.LLMResponse {
+	resp := StreamThinkingDeltaToPartialResponse(thinking)
+	resp.Content.Parts[0].ThoughtSignature = decodeSignature(signature)
+	return resp
+}
+
+// decodeSignature decodes the base64 signature of a thinking block. It returns
+// nil if the signature is not valid base64, rather than the bytes decoded up
+// to the error, since the API rejects a thinking block sent back with a
+// truncated signature; the thought is then sent back as plain text.
+func decodeSignature(signature string) []byte {
+	data, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return data
+}
@@ -15,9 +15,15 @@
 package converters
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
 	"github.com/google/jsonschema-go/jsonschema"
 	"google.golang.org/genai"
 )
@@ -44,14 +50,36 @@ func ToolsToAnthropicTools(tools []*genai.Tool) []anthropic.ToolUnionParam {
 	return result
 }
 
+// ToolConfigToToolChoice converts a genai ToolConfig to an Anthropic tool
+// choice. Only the NONE function calling mode, which keeps the tools defined
+// but stops Claude from calling them, is mapped; for other modes it returns
+// the zero value, which leaves the choice to the API default (auto).
+func ToolConfigToToolChoice(tc *genai.ToolConfig) anthropic.ToolChoiceUnionParam {
+	if tc == nil || tc.FunctionCallingConfig == nil {
+		return anthropic.ToolChoiceUnionParam{}
+	}
+	if tc.FunctionCallingConfig.Mode == genai.FunctionCallingConfigModeNone {
+		return anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}
+	}
+	return anthropic.ToolChoiceUnionParam{}
+}
+
 // FunctionDeclarationToTool converts a genai FunctionDeclaration to an Anthropic ToolUnionParam.
 //
 // If Parameters is set, it takes precedence over ParametersJsonSchema.
 // ParametersJsonSchema currently supports:
-//   - map[string]any with "properties" and "required" keys
+//   - map[string]any with "properties", "required", "additionalProperties",
+//     composition ("anyOf", "oneOf", "allOf") and reference ("$ref", "$defs",
+//     "definitions") keys
 //   - *jsonschema.Schema
 //
+// References and definitions are passed through verbatim, so recursive and
+// shared types keep resolving against the root of the input schema.
+//
 // Other ParametersJsonSchema types are ignored.
+//
+// genai.Schema cannot express additionalProperties, so closed object schemas
+// must be declared with ParametersJsonSchema.
 func FunctionDeclarationToTool(fd *genai.FunctionDeclaration) anthropic.ToolUnionParam {
 	inputSchema := anthropic.ToolInputSchemaParam{
 		// Anthropic tools require an object schema at the root.
@@ -61,13 +89,18 @@ func FunctionDeclarationToTool(fd *genai.FunctionDeclaration) anthropic.ToolUnio
 
 	// Convert parameters schema - Parameters takes precedence over ParametersJsonSchema
 	if fd.Parameters != nil {
-		props := schemaPropertiesToMap(fd.Parameters.Properties)
-		if props != nil {
+		if props := schemaProperties(fd.Parameters); props != nil {
 			inputSchema.Properties = props
 		}
 		if len(fd.Parameters.Required) > 0 {
 			inputSchema.Required = fd.Parameters.Required
 		}
+		if fd.Parameters.Title != "" {
+			setExtraField(&inputSchema, "title", fd.Parameters.Title)
+		}
+		if fd.Parameters.Description != "" {
+			setExtraField(&inputSchema, "description", fd.Parameters.Description)
+		}
 	} else if fd.ParametersJsonSchema != nil {
 		switch schema := fd.ParametersJsonSchema.(type) {
 		case map[string]any:
@@ -75,6 +108,11 @@ func FunctionDeclarationToTool(fd *genai.FunctionDeclaration) anthropic.ToolUnio
 				inputSchema.Properties = props
 			}
 			inputSchema.Required = extractRequiredFields(schema["required"])
+			for _, key := range []string{"additionalProperties", "anyOf", "oneOf", "allOf", "$ref", "$defs", "definitions"} {
+				if v, ok := schema[key]; ok {
+					setExtraField(&inputSchema, key, v)
+				}
+			}
 		case *jsonschema.Schema:
 			if props := jsonSchemaToProperties(schema); props != nil {
 				inputSchema.Properties = props
@@ -82,6 +120,21 @@ func FunctionDeclarationToTool(fd *genai.FunctionDeclaration) anthropic.ToolUnio
 			if len(schema.Required) > 0 {
 				inputSchema.Required = schema.Required
 			}
+			if schema.AdditionalProperties != nil {
+				setExtraField(&inputSchema, "additionalProperties", jsonSchemaAdditionalProperties(schema.AdditionalProperties))
+			}
+			for key, subschemas := range jsonSchemaCompositions(schema) {
+				setExtraField(&inputSchema, key, subschemas)
+			}
+			if schema.Ref != "" {
+				setExtraField(&inputSchema, "$ref", schema.Ref)
+			}
+			if defs := jsonSchemaDefinitions(schema.Defs); defs != nil {
+				setExtraField(&inputSchema, "$defs", defs)
+			}
+			if defs := jsonSchemaDefinitions(schema.Definitions); defs != nil {
+				setExtraField(&inputSchema, "definitions", defs)
+			}
 		}
 	}
 
@@ -94,6 +147,141 @@ func FunctionDeclarationToTool(fd *genai.FunctionDeclaration) anthropic.ToolUnio
 	}
 }
 
+// MakeToolSchemaStrict closes the input schema of tool and of the object
+// schemas nested or defined in it: additionalProperties is set to false and required
+// lists every property, so that Claude can neither invent arguments nor omit
+// any. Nested schemas are copied rather than modified, since they may belong
+// to the caller's ParametersJsonSchema. Tools other than custom tools are left
+// unchanged.
+func MakeToolSchemaStrict(tool anthropic.ToolUnionParam) {
+	if tool.OfTool == nil {
+		return
+	}
+	is := &tool.OfTool.InputSchema
+	setExtraField(is, "additionalProperties", false)
+	is.Required = propertyNames(is.Properties)
+	is.Properties = strictProperties(is.Properties)
+	// Objects reached through $ref are closed where they are defined
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := is.ExtraFields[key]; ok {
+			is.ExtraFields[key] = strictProperties(defs)
+		}
+	}
+}
+
+// strictSchema returns a copy of schema, a converted JSON schema, with its
+// object schemas closed.
+func strictSchema(schema any) any {
+	switch s := schema.(type) {
+	case map[string]any:
+		strict := maps.Clone(s)
+		if props, ok := s["properties"]; ok {
+			strict["additionalProperties"] = false
+			strict["required"] = propertyNames(props)
+			strict["properties"] = strictProperties(props)
+		}
+		for _, key := range []string{"items", "anyOf", "oneOf", "allOf"} {
+			if sub, ok := s[key]; ok {
+				strict[key] = strictSchema(sub)
+			}
+		}
+		for _, key := range []string{"$defs", "definitions"} {
+			if defs, ok := s[key]; ok {
+				strict[key] = strictProperties(defs)
+			}
+		}
+		return strict
+	case []map[string]any:
+		strict := make([]map[string]any, len(s))
+		for i, sub := range s {
+			strict[i], _ = strictSchema(sub).(map[string]any)
+		}
+		return strict
+	case []any:
+		strict := make([]any, len(s))
+		for i, sub := range s {
+			strict[i] = strictSchema(sub)
+		}
+		return strict
+	}
+	return schema
+}
+
+// strictProperties returns a copy of the properties of a converted JSON
+// schema with their object schemas closed.
+func strictProperties(props any) any {
+	switch p := props.(type) {
+	case map[string]any:
+		strict := make(map[string]any, len(p))
+		for name, schema := range p {
+			strict[name] = strictSchema(schema)
+		}
+		return strict
+	case orderedProperties:
+		strict := orderedProperties{keys: p.keys, values: make(map[string]any, len(p.values))}
+		for name, schema := range p.values {
+			strict.values[name] = strictSchema(schema)
+		}
+		return strict
+	}
+	return props
+}
+
+// propertyNames returns the names of the properties of a converted JSON
+// schema, in the order they are serialized.
+func propertyNames(props any) []string {
+	switch p := props.(type) {
+	case map[string]any:
+		return slices.Sorted(maps.Keys(p))
+	case orderedProperties:
+		return slices.Clone(p.keys)
+	}
+	return nil
+}
+
+// setExtraField sets a top-level input schema keyword not modelled by ToolInputSchemaParam.
+func setExtraField(inputSchema *anthropic.ToolInputSchemaParam, key string, value any) {
+	if inputSchema.ExtraFields == nil {
+		inputSchema.ExtraFields = make(map[string]any)
+	}
+	inputSchema.ExtraFields[key] = value
+}
+
+// EncodeToolSchemas replaces the input schema of each custom tool in tools
+// that has extra keywords with its JSON encoding, keywords sorted by name. The
+// SDK writes extra keywords in map iteration order, so that identical
+// requests would otherwise differ byte for byte and miss the prompt cache.
+// The schemas can no longer be modified afterwards.
+func EncodeToolSchemas(tools []anthropic.ToolUnionParam) error {
+	for _, tool := range tools {
+		if tool.OfTool == nil || len(tool.OfTool.InputSchema.ExtraFields) == 0 {
+			continue
+		}
+		schema := tool.OfTool.InputSchema
+		extras := schema.ExtraFields
+		schema.ExtraFields = nil
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return fmt.Errorf("failed to encode input schema of tool %q: %w", tool.OfTool.Name, err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("failed to encode input schema of tool %q: %w", tool.OfTool.Name, err)
+		}
+		for key, value := range extras {
+			if fields[key], err = json.Marshal(value); err != nil {
+				return fmt.Errorf("failed to encode input schema of tool %q: %w", tool.OfTool.Name, err)
+			}
+		}
+		// Maps are encoded with sorted keys
+		if data, err = json.Marshal(fields); err != nil {
+			return fmt.Errorf("failed to encode input schema of tool %q: %w", tool.OfTool.Name, err)
+		}
+		tool.OfTool.InputSchema = param.Override[anthropic.ToolInputSchemaParam](json.RawMessage(data))
+	}
+	return nil
+}
+
 // extractRequiredFields extracts required field names from various input types.
 // Supports []any (from JSON unmarshalling) and []string (from manual construction).
 func extractRequiredFields(v any) []string {
@@ -138,6 +326,9 @@ func jsonSchemaPropertyToMap(schema *jsonschema.Schema) map[string]any {
 
 	result := make(map[string]any)
 
+	if schema.Ref != "" {
+		result["$ref"] = schema.Ref
+	}
 	if schema.Type != "" {
 		result["type"] = string(schema.Type)
 	}
@@ -147,6 +338,15 @@ func jsonSchemaPropertyToMap(schema *jsonschema.Schema) map[string]any {
 	if len(schema.Enum) > 0 {
 		result["enum"] = schema.Enum
 	}
+	if schema.Const != nil {
+		result["const"] = *schema.Const
+	}
+	if len(schema.Default) > 0 {
+		result["default"] = schema.Default
+	}
+	if len(schema.Examples) > 0 {
+		result["examples"] = schema.Examples
+	}
 	if schema.Items != nil {
 		result["items"] = jsonSchemaPropertyToMap(schema.Items)
 	}
@@ -156,10 +356,131 @@ func jsonSchemaPropertyToMap(schema *jsonschema.Schema) map[string]any {
 	if len(schema.Required) > 0 {
 		result["required"] = schema.Required
 	}
+	if schema.AdditionalProperties != nil {
+		result["additionalProperties"] = jsonSchemaAdditionalProperties(schema.AdditionalProperties)
+	}
+	for key, subschemas := range jsonSchemaCompositions(schema) {
+		result[key] = subschemas
+	}
+	if defs := jsonSchemaDefinitions(schema.Defs); defs != nil {
+		result["$defs"] = defs
+	}
+	if defs := jsonSchemaDefinitions(schema.Definitions); defs != nil {
+		result["definitions"] = defs
+	}
+
+	return result
+}
+
+// jsonSchemaDefinitions converts a $defs or definitions map.
+// Returns nil if defs is empty.
+func jsonSchemaDefinitions(defs map[string]*jsonschema.Schema) map[string]any {
+	if len(defs) == 0 {
+		return nil
+	}
+	result := make(map[string]any, len(defs))
+	for name, def := range defs {
+		result[name] = jsonSchemaPropertyToMap(def)
+	}
+	return result
+}
 
+// jsonSchemaCompositions converts the anyOf, oneOf and allOf keywords of schema,
+// keyed by keyword. Empty compositions are omitted.
+func jsonSchemaCompositions(schema *jsonschema.Schema) map[string][]map[string]any {
+	result := make(map[string][]map[string]any)
+	for key, subschemas := range map[string][]*jsonschema.Schema{
+		"anyOf": schema.AnyOf,
+		"oneOf": schema.OneOf,
+		"allOf": schema.AllOf,
+	} {
+		converted := make([]map[string]any, 0, len(subschemas))
+		for _, s := range subschemas {
+			if m := jsonSchemaPropertyToMap(s); m != nil {
+				converted = append(converted, m)
+			}
+		}
+		if len(converted) > 0 {
+			result[key] = converted
+		}
+	}
 	return result
 }
 
+// jsonSchemaAdditionalProperties converts an additionalProperties schema,
+// preserving the boolean forms (false for closed objects).
+func jsonSchemaAdditionalProperties(schema *jsonschema.Schema) any {
+	b, _ := json.Marshal(schema)
+	switch string(b) {
+	case "false":
+		return false
+	case "true":
+		return true
+	}
+	return jsonSchemaPropertyToMap(schema)
+}
+
+// schemaProperties converts the properties of a genai.Schema for Anthropic.
+//
+// If the schema has a PropertyOrdering, the result is an orderedProperties that
+// serializes properties in that order, followed by any unlisted properties in
+// sorted order. Otherwise it is a map[string]any, which encoding/json
+// serializes in sorted key order. Either way the output is deterministic.
+// Returns nil if the schema has no properties.
+func schemaProperties(schema *genai.Schema) any {
+	props := schemaPropertiesToMap(schema.Properties)
+	if props == nil {
+		return nil
+	}
+	if len(schema.PropertyOrdering) == 0 {
+		return props
+	}
+
+	keys := make([]string, 0, len(props))
+	for _, key := range schema.PropertyOrdering {
+		if _, ok := props[key]; ok && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(props)) {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return orderedProperties{keys: keys, values: props}
+}
+
+// orderedProperties is a JSON schema properties object that serializes its
+// entries in a fixed order.
+type orderedProperties struct {
+	keys   []string
+	values map[string]any
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p orderedProperties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range p.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(p.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
 // schemaPropertiesToMap converts genai Schema properties to a map for Anthropic.
 func schemaPropertiesToMap(props map[string]*genai.Schema) map[string]any {
 	if props == nil {
@@ -176,6 +497,21 @@ func schemaPropertiesToMap(props map[string]*genai.Schema) map[string]any {
 	return result
 }
 
+// schemaFormats lists the JSON Schema string formats that Claude understands.
+var schemaFormats = []string{
+	"date-time", "date", "time", "duration",
+	"email", "hostname", "ipv4", "ipv6", "uri", "uuid",
+}
+
+// schemaFormat normalizes a genai.Schema format to a JSON Schema format,
+// lowercasing it and accepting underscores for hyphens (DATE_TIME). It
+// reports false for formats Claude does not understand, such as
+// FORMAT_UNSPECIFIED or the OpenAPI numeric formats, which are dropped.
+func schemaFormat(format string) (string, bool) {
+	format = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(format)), "_", "-")
+	return format, slices.Contains(schemaFormats, format)
+}
+
 // schemaToMap converts a genai.Schema to a map[string]any suitable for Anthropic.
 func schemaToMap(schema *genai.Schema) map[string]any {
 	if schema == nil {
@@ -189,19 +525,26 @@ func schemaToMap(schema *genai.Schema) map[string]any {
 		result["type"] = strings.ToLower(string(schema.Type))
 	}
 
-	// Description
+	// Title and description
+	if schema.Title != "" {
+		result["title"] = schema.Title
+	}
 	if schema.Description != "" {
 		result["description"] = schema.Description
 	}
 
-	// Enum
+	// Enum. genai.Schema has no const keyword, so a single allowed value is
+	// also emitted as const, keeping enum for validators that only read it.
 	if len(schema.Enum) > 0 {
 		result["enum"] = schema.Enum
 	}
+	if len(schema.Enum) == 1 {
+		result["const"] = schema.Enum[0]
+	}
 
 	// Format
-	if schema.Format != "" {
-		result["format"] = schema.Format
+	if format, ok := schemaFormat(schema.Format); ok {
+		result["format"] = format
 	}
 
 	// Items (for arrays)
@@ -211,7 +554,7 @@ func schemaToMap(schema *genai.Schema) map[string]any {
 
 	// Properties (for objects)
 	if len(schema.Properties) > 0 {
-		result["properties"] = schemaPropertiesToMap(schema.Properties)
+		result["properties"] = schemaProperties(schema)
 	}
 
 	// Required
@@ -229,6 +572,11 @@ func schemaToMap(schema *genai.Schema) map[string]any {
 		result["default"] = schema.Default
 	}
 
+	// Example (OpenAPI) maps to JSON Schema examples
+	if schema.Example != nil {
+		result["examples"] = []any{schema.Example}
+	}
+
 	// Min/Max constraints
 	if schema.Minimum != nil {
 		result["minimum"] = *schema.Minimum
@@ -15,14 +15,24 @@
 package anthropic
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"iter"
+	"log"
+	"maps"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"github.com/anthropics/anthropic-sdk-go/vertex"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/genai"
 
 	"google.golang.org/adk/internal/anthropicllm/converters"
@@ -31,75 +41,226 @@ import (
 
 const defaultMaxTokens = 4096
 
+// maxStopSequences is the maximum number of stop sequences accepted by the API.
+const maxStopSequences = 8191
+
+// defaultEmptyConversationPrompt is the user message sent for requests without
+// contents when Config.EmptyConversationPrompt is not set.
+const defaultEmptyConversationPrompt = "Handle the requests as specified in the System Instruction."
+
 type anthropicModel struct {
-	client           anthropic.Client
+	client           *anthropic.Client
 	name             anthropic.Model
 	variant          string
 	defaultMaxTokens int
+	// cfg is a copy of the configuration the model was created with.
+	cfg Config
+	// sem holds a token for each call in flight if
+	// Config.MaxConcurrentRequests is set.
+	sem chan struct{}
+	// fallbacks holds the clients of Config.VertexFallbackRegions, in order.
+	fallbacks []regionClient
+	// servedModelWarning logs the first response served by another model.
+	servedModelWarning sync.Once
 }
 
 // NewModel returns [model.LLM], backed by Anthropic Claude.
 //
 // It creates an Anthropic client based on the provided configuration.
+// If modelName is empty, it is read from the ANTHROPIC_MODEL environment variable.
 // If Variant is not specified, it checks the ANTHROPIC_USE_VERTEX environment variable.
 //
 // For direct Anthropic API, set APIKey in the config or the ANTHROPIC_API_KEY
 // environment variable.
 //
 // For Vertex AI, set VertexProjectID and VertexRegion in the config or use
-// GOOGLE_CLOUD_PROJECT and GOOGLE_CLOUD_REGION environment variables.
+// GOOGLE_CLOUD_PROJECT and GOOGLE_CLOUD_REGION environment variables. If
+// neither is set, the project is read from Application Default Credentials
+// and the region from the metadata server, as on GKE with workload identity.
 func NewModel(ctx context.Context, modelName anthropic.Model, cfg *Config) (model.LLM, error) {
 	if cfg == nil {
 		cfg = &Config{}
 	}
 
+	if modelName == "" {
+		modelName = anthropic.Model(os.Getenv("ANTHROPIC_MODEL"))
+	}
+	if modelName == "" {
+		return nil, fmt.Errorf("model name is required (set ANTHROPIC_MODEL)")
+	}
+
+	switch cfg.ServiceTier {
+	case "", ServiceTierAuto, ServiceTierStandardOnly:
+	default:
+		return nil, fmt.Errorf("invalid ServiceTier %q: must be %q or %q", cfg.ServiceTier, ServiceTierAuto, ServiceTierStandardOnly)
+	}
+	switch cfg.CacheTTL {
+	case "", CacheTTL5Minutes, CacheTTL1Hour:
+	default:
+		return nil, fmt.Errorf("invalid CacheTTL %q: must be %q or %q", cfg.CacheTTL, CacheTTL5Minutes, CacheTTL1Hour)
+	}
+	if cfg.ComputerUse != nil {
+		if err := cfg.ComputerUse.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range cfg.MCPServers {
+		if err := cfg.MCPServers[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	client, variant, err := newClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	maxTokens := cfg.DefaultMaxTokens
+	if maxTokens == 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	m := &anthropicModel{
+		client:           client,
+		name:             modelName,
+		variant:          variant,
+		defaultMaxTokens: maxTokens,
+		cfg:              *cfg,
+	}
+	if cfg.MaxConcurrentRequests > 0 {
+		m.sem = make(chan struct{}, cfg.MaxConcurrentRequests)
+	}
+	if variant == VariantVertexAI && len(cfg.VertexFallbackRegions) > 0 {
+		if m.fallbacks, err = newFallbackClients(ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// newClient returns the client for cfg, shared with other models unless
+// sharing is disabled, and the variant it talks to.
+func newClient(ctx context.Context, cfg *Config) (*anthropic.Client, string, error) {
 	variant := cfg.Variant
 	if variant == "" {
 		variant = GetVariant()
 	}
-
-	var client anthropic.Client
+	if variant == VariantVertexAI {
+		cfg = withVertexLocation(ctx, cfg)
+	} else if cfg.APIKey == "" {
+		apiKey, err := readAPIKeyFile(cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		if apiKey != "" {
+			withKey := *cfg
+			withKey.APIKey = apiKey
+			cfg = &withKey
+		}
+	}
+	key := newClientKey(cfg, variant)
 
 	switch variant {
 	case VariantVertexAI:
 		// Validate required Vertex AI configuration
-		projectID := cfg.VertexProjectID
-		if projectID == "" {
-			projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+		if cfg.VertexProjectID == "" {
+			return nil, "", fmt.Errorf("VertexProjectID is required for Vertex AI (set GOOGLE_CLOUD_PROJECT)")
 		}
-		if projectID == "" {
-			return nil, fmt.Errorf("VertexProjectID is required for Vertex AI (set GOOGLE_CLOUD_PROJECT)")
+		if cfg.VertexRegion == "" {
+			return nil, "", fmt.Errorf("VertexRegion is required for Vertex AI (set GOOGLE_CLOUD_REGION)")
 		}
 
-		region := cfg.VertexRegion
-		if region == "" {
-			region = os.Getenv("GOOGLE_CLOUD_REGION")
+		client, err := sharedClient(cfg, key, func() (anthropic.Client, error) { return newVertexClient(ctx, cfg) })
+		if err != nil {
+			return nil, "", err
 		}
-		if region == "" {
-			return nil, fmt.Errorf("VertexRegion is required for Vertex AI (set GOOGLE_CLOUD_REGION)")
+		return client, variant, nil
+	default:
+		client, err := sharedClient(cfg, key, func() (anthropic.Client, error) { return newAPIClient(cfg), nil })
+		if err != nil {
+			return nil, "", err
 		}
+		return client, variant, nil
+	}
+}
 
-		client = newVertexClient(ctx, cfg)
-	default:
-		client = newAPIClient(cfg)
+// clientOptions returns the request options shared by all backend variants.
+func clientOptions(cfg *Config) []option.RequestOption {
+	var opts []option.RequestOption
+	if cfg.RetryPolicy != nil {
+		opts = append(opts, option.WithMaxRetries(0))
+	}
+	if betas := betaHeaders(cfg); len(betas) > 0 {
+		opts = append(opts, option.WithHeader("anthropic-beta", strings.Join(betas, ",")))
+	}
+	if cfg.APIVersion != "" {
+		opts = append(opts, option.WithHeader("anthropic-version", cfg.APIVersion))
+	}
+	for name, value := range cfg.ExtraHeaders {
+		opts = append(opts, option.WithHeader(name, value))
 	}
+	if cfg.RecordDir != "" || cfg.ReplayDir != "" {
+		rec := &recorder{recordDir: cfg.RecordDir, replayDir: cfg.ReplayDir}
+		opts = append(opts, option.WithMiddleware(rec.middleware))
+	}
+	return opts
+}
 
-	maxTokens := cfg.DefaultMaxTokens
-	if maxTokens == 0 {
-		maxTokens = defaultMaxTokens
+// requestOptions returns opts with the headers set by [WithHeaders] on ctx.
+func requestOptions(ctx context.Context, opts ...option.RequestOption) []option.RequestOption {
+	for name, value := range headersFromContext(ctx) {
+		opts = append(opts, option.WithHeader(name, value))
 	}
+	return opts
+}
 
-	return &anthropicModel{
-		client:           client,
-		name:             modelName,
-		variant:          variant,
-		defaultMaxTokens: maxTokens,
-	}, nil
+// betaHeaders returns the beta features required or requested by the
+// configuration, without duplicates.
+func betaHeaders(cfg *Config) []string {
+	var betas []string
+	if cfg.ComputerUse != nil || cfg.BashTool || cfg.TextEditorTool {
+		betas = append(betas, computerUseBeta)
+	}
+	if len(cfg.MCPServers) > 0 {
+		betas = append(betas, mcpClientBeta)
+	}
+	if cfg.CodeExecutionTool {
+		betas = append(betas, codeExecutionBeta)
+	}
+	if cfg.InterleavedThinking {
+		betas = append(betas, interleavedThinkingBeta)
+	}
+	for _, beta := range cfg.BetaHeaders {
+		beta = strings.TrimSpace(beta)
+		if beta != "" && !slices.Contains(betas, beta) {
+			betas = append(betas, beta)
+		}
+	}
+	return betas
+}
+
+// readAPIKeyFile returns the API key read from Config.APIKeyFile, or from the
+// file named by ANTHROPIC_API_KEY_FILE, trimmed of surrounding whitespace. It
+// returns an empty key if neither is set.
+func readAPIKeyFile(cfg *Config) (string, error) {
+	path := cmp.Or(cfg.APIKeyFile, os.Getenv("ANTHROPIC_API_KEY_FILE"))
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read API key file: %w", err)
+	}
+	apiKey := strings.TrimSpace(string(data))
+	if apiKey == "" {
+		return "", fmt.Errorf("API key file %s is empty", path)
+	}
+	return apiKey, nil
 }
 
 // newAPIClient creates a client for the direct Anthropic API.
 func newAPIClient(cfg *Config) anthropic.Client {
-	opts := []option.RequestOption{}
+	opts := clientOptions(cfg)
 
 	apiKey := cfg.APIKey
 	if apiKey == "" {
@@ -113,21 +274,21 @@ func newAPIClient(cfg *Config) anthropic.Client {
 }
 
 // newVertexClient creates a client for Anthropic via Vertex AI.
-// Note: The caller must validate that projectID and region are set before calling this.
-func newVertexClient(ctx context.Context, cfg *Config) anthropic.Client {
-	projectID := cfg.VertexProjectID
-	if projectID == "" {
-		projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
-	}
-
-	region := cfg.VertexRegion
-	if region == "" {
-		region = os.Getenv("GOOGLE_CLOUD_REGION")
+// Note: The caller must resolve and validate the project and region of cfg
+// (see withVertexLocation) before calling this.
+func newVertexClient(ctx context.Context, cfg *Config) (anthropic.Client, error) {
+	projectID, region := cfg.VertexProjectID, cfg.VertexRegion
+
+	// Look up the credentials as vertex.WithGoogleAuth does, but tag token
+	// errors so that expired credentials can be reported as such
+	creds, err := google.FindDefaultCredentials(ctx)
+	if err != nil {
+		return anthropic.Client{}, fmt.Errorf("failed to find default credentials: %w", err)
 	}
+	creds.TokenSource = authTokenSource{creds.TokenSource}
 
-	return anthropic.NewClient(
-		vertex.WithGoogleAuth(ctx, region, projectID),
-	)
+	opts := append(clientOptions(cfg), vertex.WithCredentials(ctx, region, projectID, creds))
+	return anthropic.NewClient(opts...), nil
 }
 
 // Name returns the model name.
@@ -135,99 +296,301 @@ func (m *anthropicModel) Name() string {
 	return string(m.name)
 }
 
+// ClientProvider is implemented by the models returned by NewModel. It gives
+// access to the configured Anthropic client, for calling endpoints this
+// package does not wrap without setting up authentication again.
+//
+//	client := llm.(anthropic.ClientProvider).Client()
+//	page, err := client.Models.List(ctx, sdk.ModelListParams{}) // sdk is github.com/anthropics/anthropic-sdk-go
+//
+// Calls made through the client bypass this package entirely: requests and
+// responses are not converted, and hooks, tracing, metrics and retries do not
+// apply.
+type ClientProvider interface {
+	// Client returns the Anthropic client used by the model. The client may be
+	// shared with other models (see Config.DisableClientSharing).
+	Client() anthropic.Client
+}
+
+var _ ClientProvider = (*anthropicModel)(nil)
+
+// Client implements ClientProvider.
+func (m *anthropicModel) Client() anthropic.Client {
+	return *m.client
+}
+
 // GenerateContent calls the Anthropic model.
 func (m *anthropicModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
 	m.maybeAppendUserContent(req)
 
-	if stream {
-		return m.generateStream(ctx, req)
-	}
+	return m.metered(ctx, stream, m.traced(ctx, func(ctx context.Context) iter.Seq2[*model.LLMResponse, error] {
+		if stream {
+			return m.limited(ctx, m.generateStream(ctx, req))
+		}
 
-	return func(yield func(*model.LLMResponse, error) bool) {
-		resp, err := m.generate(ctx, req)
-		yield(resp, err)
-	}
+		return m.limited(ctx, func(yield func(*model.LLMResponse, error) bool) {
+			resp, err := m.generate(ctx, req)
+			yield(resp, err)
+		})
+	}))
 }
 
 // generate calls the model synchronously.
 func (m *anthropicModel) generate(ctx context.Context, req *model.LLMRequest) (*model.LLMResponse, error) {
-	params, err := m.convertRequest(req)
+	params, err := m.convertRequest(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert request: %w", err)
 	}
 
-	msg, err := m.client.Messages.New(ctx, params)
+	if m.cfg.OnRequest != nil {
+		m.cfg.OnRequest(params)
+	}
+
+	var msg *anthropic.Message
+	var raw *http.Response
+	body, extraOpts := extraFieldOptions(params)
+	opts := requestOptions(ctx, append(extraOpts, option.WithResponseInto(&raw))...)
+	err = m.withFailover(ctx, func(client *anthropic.Client) error {
+		var err error
+		msg, err = client.Messages.New(ctx, body, opts...)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to call model: %w", err)
+		if resp := errorResponse(err); resp != nil {
+			return resp, nil
+		}
+		return nil, fmt.Errorf("failed to call model: %w", classifyError(err))
+	}
+	if m.cfg.OnResponse != nil {
+		m.cfg.OnResponse(msg)
 	}
 
 	resp, err := converters.MessageToLLMResponse(msg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert response: %w", err)
 	}
+	if m.cfg.MergeTextParts {
+		converters.MergeTextParts(resp.Content)
+	}
+	if endsWithJSONPrefill(req, &params) {
+		if err := converters.RestoreJSONPrefill(resp); err != nil {
+			return nil, err
+		}
+	}
+	attachRateLimit(resp, raw)
+	m.checkServedModel(resp)
 
-	return resp, nil
+	return m.transformResponse(resp), nil
 }
 
 // generateStream returns a stream of responses from the model.
 func (m *anthropicModel) generateStream(ctx context.Context, req *model.LLMRequest) iter.Seq2[*model.LLMResponse, error] {
 	return func(yield func(*model.LLMResponse, error) bool) {
-		params, err := m.convertRequest(req)
+		params, err := m.convertRequest(ctx, req)
 		if err != nil {
 			yield(nil, fmt.Errorf("failed to convert request: %w", err))
 			return
 		}
 
-		stream := m.client.Messages.NewStreaming(ctx, params)
+		if m.cfg.OnRequest != nil {
+			m.cfg.OnRequest(params)
+		}
+
+		// Data arriving from the API, pings included, keeps the stream alive
+		streamCtx := ctx
+		var raw *http.Response
+		body, extraOpts := extraFieldOptions(params)
+		opts := requestOptions(ctx, append(extraOpts, option.WithResponseInto(&raw))...)
+		if m.cfg.StreamIdleTimeout > 0 {
+			var watchdog *idleWatchdog
+			var stop func()
+			streamCtx, watchdog, stop = newIdleWatchdog(ctx, m.cfg.StreamIdleTimeout)
+			defer stop()
+			opts = append(opts, option.WithMiddleware(watchdog.middleware))
+			yield = watchdog.pausing(yield)
+		}
+
+		// The request is sent when the stream is created, so retries and
+		// region failover happen before any event has been yielded.
+		var stream *ssestream.Stream[anthropic.MessageStreamEventUnion]
+		err = m.withFailover(ctx, func(client *anthropic.Client) error {
+			if stream != nil {
+				stream.Close()
+			}
+			stream = client.Messages.NewStreaming(streamCtx, body, opts...)
+			return stream.Err()
+		})
+		defer stream.Close()
+		if err != nil {
+			if err := idleTimeoutError(streamCtx); err != nil {
+				yield(nil, err)
+				return
+			}
+			if resp := errorResponse(err); resp != nil {
+				yield(resp, nil)
+				return
+			}
+			yield(nil, fmt.Errorf("stream error: %w", classifyError(err)))
+			return
+		}
 		message := anthropic.Message{}
+		// The JSON prefill is not echoed back, so prepend it to the first text delta
+		prefill := ""
+		if endsWithJSONPrefill(req, &params) {
+			prefill = converters.JSONPrefill
+		}
+		buf := &deltaBuffer{maxChars: m.cfg.StreamBufferChars, maxAge: m.cfg.StreamBufferDuration}
+		var blockStart anthropic.ContentBlockStartEvent
 
 		for stream.Next() {
+			// Stop consuming the stream as soon as the caller cancels
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
 			event := stream.Current()
 
 			// Accumulate the message
+			stop, isStop := event.AsAny().(anthropic.ContentBlockStopEvent)
+			if isStop {
+				converters.QuoteInvalidToolInput(&message, stop.Index)
+			}
 			if err := message.Accumulate(event); err != nil {
 				yield(nil, fmt.Errorf("failed to accumulate message: %w", err))
 				return
 			}
+			if start, ok := event.AsAny().(anthropic.ContentBlockStartEvent); ok {
+				blockStart = start
+			} else if isStop {
+				converters.RestoreServerToolBlock(&message, blockStart)
+			}
 
 			// Handle different event types for streaming
 			switch ev := event.AsAny().(type) {
+			case anthropic.MessageStartEvent:
+				// Surface input tokens early so callers can enforce budgets
+				resp := converters.StreamMessageStartToPartialResponse(ev.Message.Usage)
+				if !yield(resp, nil) {
+					return
+				}
 			case anthropic.ContentBlockDeltaEvent:
 				// Handle text deltas
+				var ready []*model.LLMResponse
 				switch delta := ev.Delta.AsAny().(type) {
 				case anthropic.TextDelta:
-					resp := converters.StreamDeltaToPartialResponse(delta.Text)
+					ready = buf.add(prefill+delta.Text, false)
+					prefill = ""
+				case anthropic.ThinkingDelta:
+					ready = buf.add(delta.Thinking, true)
+				case anthropic.SignatureDelta:
+					ready = buf.sign(delta.Signature)
+				case anthropic.CitationsDelta:
+					// Keep citations after the text buffered before them
+					if resp := buf.flush(); resp != nil {
+						ready = append(ready, resp)
+					}
+					if resp := converters.StreamCitationToPartialResponse(delta.Citation); resp != nil {
+						ready = append(ready, resp)
+					}
+				}
+				for _, resp := range ready {
 					if !yield(resp, nil) {
 						return
 					}
-				case anthropic.ThinkingDelta:
-					resp := converters.StreamThinkingDeltaToPartialResponse(delta.Thinking)
+				}
+			case anthropic.ContentBlockStopEvent:
+				if resp := buf.flush(); resp != nil {
 					if !yield(resp, nil) {
 						return
 					}
 				}
+			case anthropic.MessageDeltaEvent:
+				converters.AccumulateContainer(&message, ev)
+				if ev.Delta.StopReason != "" {
+					if resp := buf.flush(); resp != nil {
+						if !yield(resp, nil) {
+							return
+						}
+					}
+					if !yield(converters.StreamStopReasonToPartialResponse(ev.Delta.StopReason), nil) {
+						return
+					}
+				}
 			}
 		}
 
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := idleTimeoutError(streamCtx); err != nil {
+			yield(nil, err)
+			return
+		}
 		if err := stream.Err(); err != nil {
-			yield(nil, fmt.Errorf("stream error: %w", err))
+			yield(nil, fmt.Errorf("stream error: %w", classifyError(err)))
 			return
 		}
 
+		if m.cfg.OnResponse != nil {
+			m.cfg.OnResponse(&message)
+		}
+
 		// Yield the final complete response
 		finalResp, err := converters.MessageToLLMResponse(&message)
 		if err != nil {
 			yield(nil, fmt.Errorf("failed to convert stream response: %w", err))
 			return
 		}
-		finalResp.TurnComplete = true
-		yield(finalResp, nil)
+		if m.cfg.MergeTextParts {
+			converters.MergeTextParts(finalResp.Content)
+		}
+		if endsWithJSONPrefill(req, &params) {
+			if err := converters.RestoreJSONPrefill(finalResp); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+		attachRateLimit(finalResp, raw)
+		m.checkServedModel(finalResp)
+		yield(m.transformResponse(finalResp), nil)
+	}
+}
+
+// checkServedModel records the requested model on resp if another model served
+// it, and logs a warning the first time, since silently substituted models
+// make evaluations hard to compare.
+func (m *anthropicModel) checkServedModel(resp *model.LLMResponse) {
+	if !converters.CheckServedModel(resp, string(m.name)) {
+		return
 	}
+	m.servedModelWarning.Do(func() {
+		log.Printf("anthropic: requested model %s but the response was served by %s", m.name, resp.CustomMetadata[MetadataKeyModel])
+	})
+}
+
+// transformResponse applies Config.ResponseTransform to the final response.
+func (m *anthropicModel) transformResponse(resp *model.LLMResponse) *model.LLMResponse {
+	if m.cfg.ResponseTransform == nil {
+		return resp
+	}
+	if transformed := m.cfg.ResponseTransform(resp); transformed != nil {
+		return transformed
+	}
+	return resp
 }
 
 // convertRequest converts an LLMRequest to Anthropic MessageNewParams.
-func (m *anthropicModel) convertRequest(req *model.LLMRequest) (anthropic.MessageNewParams, error) {
-	messages, err := converters.ContentsToMessages(req.Contents)
+func (m *anthropicModel) convertRequest(ctx context.Context, req *model.LLMRequest) (anthropic.MessageNewParams, error) {
+	contents := req.Contents
+	if m.cfg.MaxImageDimension > 0 {
+		contents = downscaleImages(contents, m.cfg.MaxImageDimension)
+	}
+	if err := checkImageLimits(contents, &m.cfg); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+	messages, err := converters.ContentsToMessages(contents)
 	if err != nil {
 		return anthropic.MessageNewParams{}, fmt.Errorf("failed to convert contents: %w", err)
 	}
@@ -238,7 +601,26 @@ func (m *anthropicModel) convertRequest(req *model.LLMRequest) (anthropic.Messag
 		MaxTokens: int64(m.defaultMaxTokens),
 	}
 
+	if m.cfg.ServiceTier != "" {
+		params.ServiceTier = anthropic.MessageNewParamsServiceTier(m.cfg.ServiceTier)
+	}
+
+	userID := userIDFromContext(ctx)
+	if userID == "" {
+		userID = m.cfg.DefaultUserID
+	}
+	if userID != "" {
+		params.Metadata.UserID = anthropic.String(userID)
+	}
+	if container := containerFromContext(ctx); container != "" {
+		setExtraField(&params, "container", container)
+	}
+
 	if req.Config != nil {
+		if err := checkUnsupportedParams(req.Config); err != nil {
+			return anthropic.MessageNewParams{}, err
+		}
+
 		// System instruction
 		if req.Config.SystemInstruction != nil {
 			params.System = converters.SystemInstructionToSystem(req.Config.SystemInstruction)
@@ -254,9 +636,6 @@ func (m *anthropicModel) convertRequest(req *model.LLMRequest) (anthropic.Messag
 		if req.Config.TopK != nil {
 			params.TopK = anthropic.Int(int64(*req.Config.TopK))
 		}
-		if len(req.Config.StopSequences) > 0 {
-			params.StopSequences = req.Config.StopSequences
-		}
 		if req.Config.MaxOutputTokens > 0 {
 			params.MaxTokens = int64(req.Config.MaxOutputTokens)
 		}
@@ -265,22 +644,249 @@ func (m *anthropicModel) convertRequest(req *model.LLMRequest) (anthropic.Messag
 		if len(req.Config.Tools) > 0 {
 			params.Tools = converters.ToolsToAnthropicTools(req.Config.Tools)
 		}
+		params.ToolChoice = converters.ToolConfigToToolChoice(req.Config.ToolConfig)
+		if m.cfg.StrictToolSchemas {
+			for _, tool := range params.Tools {
+				converters.MakeToolSchemaStrict(tool)
+			}
+		}
+		if err := converters.EncodeToolSchemas(params.Tools); err != nil {
+			return anthropic.MessageNewParams{}, err
+		}
+	}
+	params.System = append(params.System, converters.SystemContentsToSystem(contents)...)
+	if m.cfg.SystemPrefix != "" {
+		params.System = slices.Insert(params.System, 0, anthropic.TextBlockParam{Text: m.cfg.SystemPrefix})
+	}
+	if m.cfg.SystemSuffix != "" {
+		params.System = append(params.System, anthropic.TextBlockParam{Text: m.cfg.SystemSuffix})
+	}
+
+	if maxTokens := maxTokensFromContext(ctx); maxTokens > 0 {
+		params.MaxTokens = int64(maxTokens)
+	}
+
+	var reqStopSequences []string
+	if req.Config != nil {
+		reqStopSequences = req.Config.StopSequences
+	}
+	stopSequences, err := mergeStopSequences(m.cfg.DefaultStopSequences, reqStopSequences)
+	if err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+	params.StopSequences = stopSequences
+
+	// Model defaults apply to parameters the request leaves unset
+	if !params.Temperature.Valid() && m.cfg.DefaultTemperature != nil {
+		params.Temperature = anthropic.Float(*m.cfg.DefaultTemperature)
+	}
+	if temperature, ok := m.modelTemperature(); ok && !params.Temperature.Valid() {
+		params.Temperature = anthropic.Float(temperature)
+	}
+	if !params.TopP.Valid() && m.cfg.DefaultTopP != nil {
+		params.TopP = anthropic.Float(*m.cfg.DefaultTopP)
+	}
+	if !params.TopK.Valid() && m.cfg.DefaultTopK != nil {
+		params.TopK = anthropic.Int(int64(*m.cfg.DefaultTopK))
+	}
+
+	if err := m.applyThinking(req, &params); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	if err := validateSamplingParams(&params); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	params.Tools = applyBuiltinTools(&m.cfg, params.Tools)
+	if m.cfg.CacheTools {
+		cacheTools(params.Tools)
+	}
+	if m.cfg.CacheTTL != "" {
+		setCacheTTL(&params, anthropic.CacheControlEphemeralTTL(m.cfg.CacheTTL))
+	}
+
+	if err := checkCacheBreakpoints(&params); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+
+	if len(m.cfg.MCPServers) > 0 {
+		setExtraField(&params, "mcp_servers", mcpServersParam(m.cfg.MCPServers))
+	}
+
+	// Anthropic has no JSON mode; prefill the answer to force a JSON object
+	prefill := canPrefillJSON(req, &params)
+	if prefill {
+		params.Messages = append(params.Messages, converters.JSONPrefillMessage())
+	}
+
+	if m.cfg.AutoTruncate {
+		if err := m.truncateToFit(ctx, contents, &params, prefill); err != nil {
+			return anthropic.MessageNewParams{}, err
+		}
+	} else if m.cfg.EnforceContextLimit {
+		if err := m.checkContextLimit(ctx, &params); err != nil {
+			return anthropic.MessageNewParams{}, err
+		}
 	}
 
 	return params, nil
 }
 
+// checkUnsupportedParams returns an error naming the parameters set in cfg
+// that Anthropic models do not support, rather than silently ignoring them.
+func checkUnsupportedParams(cfg *genai.GenerateContentConfig) error {
+	// Generating candidates internally would multiply the cost of a call
+	// behind the caller's back, so leave that choice to the caller.
+	if cfg.CandidateCount > 1 {
+		return fmt.Errorf("CandidateCount %d is not supported: Anthropic models return a single candidate per request; call GenerateContent once per candidate instead", cfg.CandidateCount)
+	}
+
+	if cfg.ResponseLogprobs || cfg.Logprobs != nil {
+		return fmt.Errorf("ResponseLogprobs and Logprobs are not supported: the Anthropic API does not return token log probabilities")
+	}
+
+	var unsupported []string
+	if cfg.Seed != nil {
+		unsupported = append(unsupported, "Seed")
+	}
+	if cfg.PresencePenalty != nil {
+		unsupported = append(unsupported, "PresencePenalty")
+	}
+	if cfg.FrequencyPenalty != nil {
+		unsupported = append(unsupported, "FrequencyPenalty")
+	}
+	if slices.ContainsFunc(cfg.ResponseModalities, func(m string) bool { return m != string(genai.ModalityText) }) {
+		unsupported = append(unsupported, "ResponseModalities other than TEXT")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("unsupported generation parameters for Anthropic models: %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// mergeStopSequences combines the default and per-request stop sequences,
+// dropping empty and duplicate sequences. It returns an error if the result
+// exceeds the number of stop sequences accepted by the API.
+func mergeStopSequences(defaults, requested []string) ([]string, error) {
+	var merged []string
+	seen := make(map[string]bool)
+	for _, seq := range slices.Concat(defaults, requested) {
+		if seq == "" || seen[seq] {
+			continue
+		}
+		seen[seq] = true
+		merged = append(merged, seq)
+	}
+	if len(merged) > maxStopSequences {
+		return nil, fmt.Errorf("too many stop sequences: got %d, maximum is %d", len(merged), maxStopSequences)
+	}
+	return merged, nil
+}
+
+// validateSamplingParams checks the sampling parameters against the ranges
+// accepted by the API, so that callers get a descriptive error rather than an
+// opaque 400 response.
+func validateSamplingParams(params *anthropic.MessageNewParams) error {
+	if t := params.Temperature; t.Valid() && (t.Value < 0 || t.Value > 1) {
+		return fmt.Errorf("invalid temperature %v: must be between 0 and 1", t.Value)
+	}
+	if p := params.TopP; p.Valid() && (p.Value < 0 || p.Value > 1) {
+		return fmt.Errorf("invalid top_p %v: must be between 0 and 1", p.Value)
+	}
+	if k := params.TopK; k.Valid() && k.Value < 0 {
+		return fmt.Errorf("invalid top_k %v: must not be negative", k.Value)
+	}
+	return nil
+}
+
+// setExtraField sets a request field that the SDK does not model, keeping any
+// extra fields already set.
+func setExtraField(params *anthropic.MessageNewParams, key string, value any) {
+	extras := params.ExtraFields()
+	if extras == nil {
+		extras = make(map[string]any)
+	}
+	extras[key] = value
+	params.SetExtraFields(extras)
+}
+
+// extraFieldOptions returns params without its extra fields, and request
+// options that set them in the request body in order of their names. The SDK
+// writes extra fields in map iteration order, so that a request with several
+// of them would not serialize to the same bytes twice.
+func extraFieldOptions(params anthropic.MessageNewParams) (anthropic.MessageNewParams, []option.RequestOption) {
+	extras := params.ExtraFields()
+	if len(extras) == 0 {
+		return params, nil
+	}
+	params.SetExtraFields(nil)
+	var opts []option.RequestOption
+	for _, key := range slices.Sorted(maps.Keys(extras)) {
+		opts = append(opts, option.WithJSONSet(param.EscapeSJSONKey(key), extras[key]))
+	}
+	return params, opts
+}
+
+// wantsJSON reports whether the request asks for JSON-only output.
+func wantsJSON(req *model.LLMRequest) bool {
+	return req.Config != nil && req.Config.ResponseMIMEType == converters.JSONMIMEType
+}
+
+// canPrefillJSON reports whether the answer to req, made with params, is to be
+// prefilled with converters.JSONPrefill. The API rejects a prefilled answer
+// when thinking is enabled, and a prefill after an assistant turn, as when the
+// history ends with unanswered function calls, would be a second assistant
+// message in a row. The prefill is skipped in both cases, and JSON output then
+// relies on the prompt alone.
+func canPrefillJSON(req *model.LLMRequest, params *anthropic.MessageNewParams) bool {
+	if !wantsJSON(req) || params.Thinking.OfEnabled != nil {
+		return false
+	}
+	n := len(params.Messages)
+	return n > 0 && params.Messages[n-1].Role != anthropic.MessageParamRoleAssistant
+}
+
+// endsWithJSONPrefill reports whether req asks for JSON output and the
+// messages of params end with a JSON prefill, as added by canPrefillJSON,
+// whose text the response omits.
+func endsWithJSONPrefill(req *model.LLMRequest, params *anthropic.MessageNewParams) bool {
+	n := len(params.Messages)
+	if !wantsJSON(req) || n == 0 {
+		return false
+	}
+	last := params.Messages[n-1]
+	return last.Role == anthropic.MessageParamRoleAssistant && len(last.Content) == 1 &&
+		last.Content[0].OfText != nil && last.Content[0].OfText.Text == converters.JSONPrefill
+}
+
 // maybeAppendUserContent ensures the conversation ends with a user message.
 // Anthropic requires strictly alternating user/assistant turns.
+//
+// A model turn that ends with function calls is left alone: the API requires
+// the next user turn to carry their results, so appending plain text would
+// only turn a missing tool result into a confusing error.
 func (m *anthropicModel) maybeAppendUserContent(req *model.LLMRequest) {
+	if m.cfg.DisableAutoUserContent {
+		return
+	}
 	if len(req.Contents) == 0 {
-		req.Contents = append(req.Contents,
-			genai.NewContentFromText("Handle the requests as specified in the System Instruction.", "user"))
+		prompt := cmp.Or(m.cfg.EmptyConversationPrompt, defaultEmptyConversationPrompt)
+		req.Contents = append(req.Contents, genai.NewContentFromText(prompt, "user"))
 		return
 	}
 
-	if last := req.Contents[len(req.Contents)-1]; last != nil && last.Role != "user" {
-		req.Contents = append(req.Contents,
-			genai.NewContentFromText("Continue processing previous requests as instructed.", "user"))
+	last := req.Contents[len(req.Contents)-1]
+	if last == nil || last.Role == "user" || last.Role == "tool" || hasFunctionCall(last) {
+		return
 	}
+	req.Contents = append(req.Contents,
+		genai.NewContentFromText("Continue processing previous requests as instructed.", "user"))
+}
+
+// hasFunctionCall reports whether content contains a function call.
+func hasFunctionCall(content *genai.Content) bool {
+	return slices.ContainsFunc(content.Parts, func(p *genai.Part) bool {
+		return p != nil && p.FunctionCall != nil
+	})
 }
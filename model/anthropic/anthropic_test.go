@@ -15,10 +15,100 @@
 package anthropic
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
 )
 
+// okMessage is a non-streaming Messages API response with a single "ok" text block.
+const okMessage = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`
+
+// newTestModel returns a model whose client talks to an httptest server running handler.
+func newTestModel(t *testing.T, cfg *Config, handler http.HandlerFunc) *anthropicModel {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	t.Setenv("ANTHROPIC_BASE_URL", srv.URL)
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = "test-api-key"
+	}
+	if cfg.Variant == "" {
+		cfg.Variant = VariantAnthropicAPI
+	}
+
+	m, err := NewModel(t.Context(), "claude-sonnet-4-20250514", cfg)
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+	return m.(*anthropicModel)
+}
+
+// writeSSE writes each JSON event as a server-sent event, using its "type" as the event name.
+func writeSSE(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, ev := range events {
+		typ := ev[strings.Index(ev, `"type":"`)+len(`"type":"`):]
+		typ = typ[:strings.Index(typ, `"`)]
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ, ev)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// writeJSON writes body as a JSON response.
+func writeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, body)
+}
+
+// textStreamEvents returns the SSE events for a streamed single text block response.
+func textStreamEvents(text, stopReason string) []string {
+	return []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		fmt.Sprintf(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":%q}}`, text),
+		`{"type":"content_block_stop","index":0}`,
+		fmt.Sprintf(`{"type":"message_delta","delta":{"stop_reason":%q,"stop_sequence":null},"usage":{"output_tokens":15}}`, stopReason),
+		`{"type":"message_stop"}`,
+	}
+}
+
+// collect drains a GenerateContent iterator, failing the test on error.
+func collect(t *testing.T, m model.LLM, req *model.LLMRequest, stream bool) []*model.LLMResponse {
+	t.Helper()
+	var got []*model.LLMResponse
+	for resp, err := range m.GenerateContent(t.Context(), req, stream) {
+		if err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		got = append(got, resp)
+	}
+	return got
+}
+
 func TestNewModel_ConfigBehavior(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -69,6 +159,66 @@ func TestNewModel_ConfigBehavior(t *testing.T) {
 	}
 }
 
+func TestNewModel_APIKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	envKeyFile := filepath.Join(dir, "env-key")
+	if err := os.WriteFile(keyFile, []byte("  file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(envKeyFile, []byte("env-file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		envKeyFile string
+		want       string
+		wantErr    string
+	}{
+		{name: "api_key", cfg: Config{APIKey: "explicit-key", APIKeyFile: keyFile}, envKeyFile: envKeyFile, want: "explicit-key"},
+		{name: "api_key_file", cfg: Config{APIKeyFile: keyFile}, envKeyFile: envKeyFile, want: "file-key"},
+		{name: "env_key_file", envKeyFile: envKeyFile, want: "env-file-key"},
+		{name: "env_key", want: "env-key"},
+		{name: "missing_file", cfg: Config{APIKeyFile: filepath.Join(dir, "missing")}, wantErr: "failed to read API key file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("X-Api-Key")
+				writeJSON(w, okMessage)
+			}))
+			t.Cleanup(srv.Close)
+			t.Setenv("ANTHROPIC_BASE_URL", srv.URL)
+			t.Setenv("ANTHROPIC_API_KEY", "env-key")
+			t.Setenv("ANTHROPIC_API_KEY_FILE", tt.envKeyFile)
+
+			cfg := tt.cfg
+			cfg.Variant = VariantAnthropicAPI
+			cfg.DisableClientSharing = true
+			m, err := NewModel(t.Context(), "claude-sonnet-4-20250514", &cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewModel() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewModel() error = %v", err)
+			}
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			collect(t, m, req, false)
+			if got != tt.want {
+				t.Errorf("X-Api-Key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNewModel_VertexAI_MissingConfig(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -84,6 +234,9 @@ func TestNewModel_VertexAI_MissingConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Setenv("GOOGLE_CLOUD_PROJECT", tt.project)
 			t.Setenv("GOOGLE_CLOUD_REGION", tt.region)
+			// Nothing can be resolved from Application Default Credentials
+			t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+			fakeMetadataServer(t, "")
 
 			cfg := &Config{Variant: VariantVertexAI}
 			_, err := NewModel(t.Context(), "claude-sonnet-4-20250514", cfg)
@@ -94,4 +247,1310 @@ func TestNewModel_VertexAI_MissingConfig(t *testing.T) {
 	}
 }
 
+func TestGenerateStream_MessageStartUsage(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, textStreamEvents("Hello", "end_turn")...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, true)
+	if len(got) != 4 {
+		t.Fatalf("got %d responses, want 4", len(got))
+	}
+
+	first := got[0]
+	if !first.Partial || first.Content != nil {
+		t.Errorf("first response = %+v, want partial usage-only response", first)
+	}
+	if first.UsageMetadata == nil || first.UsageMetadata.PromptTokenCount != 25 {
+		t.Errorf("first UsageMetadata = %+v, want PromptTokenCount 25", first.UsageMetadata)
+	}
+
+	final := got[len(got)-1]
+	if final.UsageMetadata.TotalTokenCount != 40 {
+		t.Errorf("final TotalTokenCount = %d, want 40", final.UsageMetadata.TotalTokenCount)
+	}
+}
+
+func TestGenerate_FinalResponseParity(t *testing.T) {
+	final := make(map[bool]*model.LLMResponse)
+	for _, stream := range []bool{false, true} {
+		m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+			if stream {
+				writeSSE(w, textStreamEvents("ok", "end_turn")...)
+				return
+			}
+			writeJSON(w, okMessage)
+		})
+
+		req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+		got := collect(t, m, req, stream)
+		final[stream] = got[len(got)-1]
+	}
+
+	if !final[false].TurnComplete {
+		t.Errorf("non-streaming response = %+v, want TurnComplete", final[false])
+	}
+	if diff := cmp.Diff(final[true], final[false]); diff != "" {
+		t.Errorf("final response mismatch (-stream +non-stream):\n%s", diff)
+	}
+}
+
+func TestGenerate_MergeTextParts(t *testing.T) {
+	const message = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[` +
+		`{"type":"text","text":"Let me "},{"type":"text","text":"check."},` +
+		`{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}},` +
+		`{"type":"text","text":"Done."}],` +
+		`"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`
+
+	for _, merge := range []bool{false, true} {
+		t.Run(fmt.Sprintf("merge=%v", merge), func(t *testing.T) {
+			m := newTestModel(t, &Config{MergeTextParts: merge}, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, message)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			var got []string
+			for _, part := range collect(t, m, req, false)[0].Content.Parts {
+				if part.FunctionCall != nil {
+					got = append(got, "call:"+part.FunctionCall.Name)
+					continue
+				}
+				got = append(got, part.Text)
+			}
+
+			want := []string{"Let me ", "check.", "call:lookup", "Done."}
+			if merge {
+				want = []string{"Let me check.", "call:lookup", "Done."}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("parts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateStream_Citations(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		events := textStreamEvents("The grass is green.", "end_turn")
+		citation := `{"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"char_location","cited_text":"The grass is green.","document_index":0,"document_title":"Facts","start_char_index":0,"end_char_index":20}}}`
+		writeSSE(w, slices.Insert(events, 2, citation)...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{{Role: "user", Parts: []*genai.Part{
+		{InlineData: &genai.Blob{MIMEType: "text/plain", Data: []byte("The grass is green. The sky is blue.")}},
+		{Text: "What color is the grass?"},
+	}}}}
+	got := collect(t, m, req, true)
+
+	want := []*genai.Citation{{Title: "Facts", StartIndex: 0, EndIndex: 20}}
+	var partial []*genai.Citation
+	for _, resp := range got[:len(got)-1] {
+		if resp.CitationMetadata != nil {
+			partial = append(partial, resp.CitationMetadata.Citations...)
+		}
+	}
+	if diff := cmp.Diff(want, partial); diff != "" {
+		t.Errorf("partial citations mismatch (-want +got):\n%s", diff)
+	}
+	final := got[len(got)-1]
+	if final.CitationMetadata == nil {
+		t.Fatal("final CitationMetadata = nil, want the citation")
+	}
+	if diff := cmp.Diff(want, final.CitationMetadata.Citations); diff != "" {
+		t.Errorf("final citations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerate_TruncatedToolCall(t *testing.T) {
+	const partial = `{"path":"notes.txt","content":"Hel`
+	tests := []struct {
+		name   string
+		stream bool
+		write  func(w http.ResponseWriter)
+	}{
+		{
+			name: "non_streaming",
+			write: func(w http.ResponseWriter) {
+				// The API returns the part of the input it could parse
+				writeJSON(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"tool_use","id":"toolu_1","name":"write","input":{"path":"notes.txt"}}],"stop_reason":"max_tokens","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`)
+			},
+		},
+		{
+			name:   "streaming",
+			stream: true,
+			write: func(w http.ResponseWriter) {
+				writeSSE(w,
+					`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+					`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"write","input":{}}}`,
+					fmt.Sprintf(`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":%q}}`, partial),
+					`{"type":"content_block_stop","index":0}`,
+					`{"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":15}}`,
+					`{"type":"message_stop"}`,
+				)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				tt.write(w)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Write notes", "user")}}
+			got := collect(t, m, req, tt.stream)
+			final := got[len(got)-1]
+
+			if final.FinishReason != genai.FinishReasonMaxTokens {
+				t.Errorf("FinishReason = %v, want %v", final.FinishReason, genai.FinishReasonMaxTokens)
+			}
+			if id := final.CustomMetadata[MetadataKeyTruncatedToolCall]; id != "toolu_1" {
+				t.Errorf("CustomMetadata[%q] = %v, want %q", MetadataKeyTruncatedToolCall, id, "toolu_1")
+			}
+			want := `{"path":"notes.txt"}`
+			if tt.stream {
+				want = partial
+			}
+			if diff := cmp.Diff(map[string]any{RawToolInputKey: want}, final.Content.Parts[0].FunctionCall.Args); diff != "" {
+				t.Errorf("Args mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateStream_PartialFinishReason(t *testing.T) {
+	m := newTestModel(t, &Config{StreamBufferChars: 1000}, func(w http.ResponseWriter, r *http.Request) {
+		events := textStreamEvents("Truncated answ", "max_tokens")
+		// Leave the text block open so the buffered text is flushed by the
+		// message_delta event
+		events = slices.Delete(events, 3, 4)
+		writeSSE(w, events...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, true)
+	if len(got) != 4 {
+		t.Fatalf("got %d responses, want 4", len(got))
+	}
+
+	if text := got[1]; !text.Partial || text.Content.Parts[0].Text != "Truncated answ" {
+		t.Errorf("second response = %+v, want the buffered text", text)
+	}
+	if stop := got[2]; !stop.Partial || stop.Content != nil || stop.FinishReason != genai.FinishReasonMaxTokens {
+		t.Errorf("third response = %+v, want a partial with FinishReasonMaxTokens", stop)
+	}
+	if final := got[3]; final.Partial || final.FinishReason != genai.FinishReasonMaxTokens {
+		t.Errorf("final response = %+v, want FinishReasonMaxTokens", final)
+	}
+}
+
+func TestGenerate_StopSequence(t *testing.T) {
+	var gotBody string
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		writeJSON(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"part one"}],"stop_reason":"stop_sequence","stop_sequence":"###","usage":{"input_tokens":25,"output_tokens":15}}`)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config:   &genai.GenerateContentConfig{StopSequences: []string{"###"}},
+	}
+	got := collect(t, m, req, false)
+	if len(got) != 1 {
+		t.Fatalf("got %d responses, want 1", len(got))
+	}
+
+	if !strings.Contains(gotBody, `"stop_sequences":["###"]`) {
+		t.Errorf("request body = %s, want stop_sequences", gotBody)
+	}
+	if got[0].FinishReason != genai.FinishReasonStop {
+		t.Errorf("FinishReason = %v, want %v", got[0].FinishReason, genai.FinishReasonStop)
+	}
+	if seq := got[0].CustomMetadata[MetadataKeyStopSequence]; seq != "###" {
+		t.Errorf("CustomMetadata[%q] = %v, want %q", MetadataKeyStopSequence, seq, "###")
+	}
+}
+
+func TestServiceTier(t *testing.T) {
+	var gotBody string
+	m := newTestModel(t, &Config{ServiceTier: ServiceTierStandardOnly}, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		writeJSON(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15,"service_tier":"standard"}}`)
+	})
 
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, false)
+
+	if !strings.Contains(gotBody, `"service_tier":"standard_only"`) {
+		t.Errorf("request body = %s, want service_tier", gotBody)
+	}
+	if tier := got[0].CustomMetadata[MetadataKeyServiceTier]; tier != "standard" {
+		t.Errorf("CustomMetadata[%q] = %v, want %q", MetadataKeyServiceTier, tier, "standard")
+	}
+}
+
+func TestNewModel_InvalidServiceTier(t *testing.T) {
+	_, err := NewModel(t.Context(), "claude-sonnet-4-20250514", &Config{APIKey: "test-api-key", ServiceTier: "priority"})
+	if err == nil || !strings.Contains(err.Error(), "invalid ServiceTier") {
+		t.Fatalf("NewModel() error = %v, want invalid ServiceTier", err)
+	}
+}
+
+func TestConvertRequest_UserID(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultUserID string
+		ctxUserID     string
+		want          string
+	}{
+		{"unset", "", "", ""},
+		{"config_default", "user-hash-1", "", "user-hash-1"},
+		{"context_overrides_default", "user-hash-1", "user-hash-2", "user-hash-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens, cfg: Config{DefaultUserID: tt.defaultUserID}}
+			ctx := t.Context()
+			if tt.ctxUserID != "" {
+				ctx = WithUserID(ctx, tt.ctxUserID)
+			}
+
+			params, err := m.convertRequest(ctx, &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}})
+			if err != nil {
+				t.Fatalf("convertRequest() error = %v", err)
+			}
+			if got := params.Metadata.UserID.Value; got != tt.want {
+				t.Errorf("Metadata.UserID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRequest_MaxTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfgDefault int
+		reqMax     int32
+		ctxMax     int
+		want       int64
+	}{
+		{name: "package_default", want: defaultMaxTokens},
+		{name: "config_default", cfgDefault: 1000, want: 1000},
+		{name: "request_overrides_config", cfgDefault: 1000, reqMax: 2000, want: 2000},
+		{name: "context_overrides_request", cfgDefault: 1000, reqMax: 2000, ctxMax: 3000, want: 3000},
+		{name: "context_overrides_config", cfgDefault: 1000, ctxMax: 3000, want: 3000},
+		{name: "invalid_context_ignored", reqMax: 2000, ctxMax: -1, want: 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens}
+			if tt.cfgDefault > 0 {
+				m.defaultMaxTokens = tt.cfgDefault
+			}
+			ctx := t.Context()
+			if tt.ctxMax != 0 {
+				ctx = WithMaxTokens(ctx, tt.ctxMax)
+			}
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+				Config:   &genai.GenerateContentConfig{MaxOutputTokens: tt.reqMax},
+			}
+
+			params, err := m.convertRequest(ctx, req)
+			if err != nil {
+				t.Fatalf("convertRequest() error = %v", err)
+			}
+			if params.MaxTokens != tt.want {
+				t.Errorf("MaxTokens = %d, want %d", params.MaxTokens, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateStream_ContextCancellation(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		events := textStreamEvents("Hello", "end_turn")
+		writeSSE(w, events[:3]...)
+		// Hold the stream open until the client goes away
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	done := make(chan error, 1)
+	go func() {
+		var lastErr error
+		for resp, err := range m.GenerateContent(ctx, req, true) {
+			if err != nil {
+				lastErr = err
+				break
+			}
+			if resp.Content != nil {
+				cancel()
+			}
+		}
+		done <- lastErr
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("GenerateContent() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GenerateContent() did not stop after context cancellation")
+	}
+}
+
+func TestGenerate_RateLimitHeaders(t *testing.T) {
+	reset := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+				w.Header().Set("anthropic-ratelimit-requests-remaining", "49")
+				w.Header().Set("anthropic-ratelimit-requests-reset", reset.Format(time.RFC3339))
+				w.Header().Set("anthropic-ratelimit-tokens-remaining", "12000")
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, stream)
+
+			rl, ok := got[len(got)-1].CustomMetadata[MetadataKeyRateLimit].(*RateLimit)
+			if !ok {
+				t.Fatalf("CustomMetadata[%q] = %v, want *RateLimit", MetadataKeyRateLimit, got[len(got)-1].CustomMetadata)
+			}
+			want := &RateLimit{RequestsLimit: 50, RequestsRemaining: 49, RequestsReset: reset, TokensRemaining: 12000}
+			if diff := cmp.Diff(want, rl); diff != "" {
+				t.Errorf("RateLimit mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerate_JSONOutput(t *testing.T) {
+	// The API omits the "{" prefill from the response
+	const answer = `"city":"Paris","population":2100000}`
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			var gotBody string
+			m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				gotBody = string(b)
+				if stream {
+					writeSSE(w, textStreamEvents(answer, "end_turn")...)
+					return
+				}
+				writeJSON(w, fmt.Sprintf(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":%q}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`, answer))
+			})
+
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Describe Paris as JSON", "user")},
+				Config:   &genai.GenerateContentConfig{ResponseMIMEType: "application/json"},
+			}
+			got := collect(t, m, req, stream)
+
+			if !strings.Contains(gotBody, `{"content":[{"text":"{","type":"text"}],"role":"assistant"}]`) {
+				t.Errorf("request body = %s, want trailing assistant prefill", gotBody)
+			}
+
+			final := got[len(got)-1]
+			var v map[string]any
+			if err := json.Unmarshal([]byte(final.Content.Parts[0].Text), &v); err != nil {
+				t.Fatalf("final text %q is not valid JSON: %v", final.Content.Parts[0].Text, err)
+			}
+			if v["city"] != "Paris" {
+				t.Errorf("city = %v, want Paris", v["city"])
+			}
+
+			if stream {
+				var text strings.Builder
+				for _, resp := range got[:len(got)-1] {
+					if resp.Content != nil {
+						text.WriteString(resp.Content.Parts[0].Text)
+					}
+				}
+				if text.String() != final.Content.Parts[0].Text {
+					t.Errorf("streamed text = %q, want %q", text.String(), final.Content.Parts[0].Text)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate_JSONOutputWithoutPrefill(t *testing.T) {
+	const answer = `{"city":"Paris"}`
+	tests := []struct {
+		name     string
+		cfg      Config
+		contents []*genai.Content
+		genCfg   *genai.GenerateContentConfig
+	}{
+		{
+			name:     "thinking",
+			contents: []*genai.Content{genai.NewContentFromText("Describe Paris as JSON", "user")},
+			genCfg:   &genai.GenerateContentConfig{ThinkingConfig: &genai.ThinkingConfig{IncludeThoughts: true}},
+		},
+		{
+			name: "history_ends_with_assistant",
+			cfg:  Config{DisableAutoUserContent: true},
+			contents: []*genai.Content{
+				genai.NewContentFromText("Describe Paris as JSON", "user"),
+				genai.NewContentFromText("Here it is:", "model"),
+			},
+			genCfg: &genai.GenerateContentConfig{},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%v", tt.name, stream), func(t *testing.T) {
+				var gotBody string
+				m := newTestModel(t, &tt.cfg, func(w http.ResponseWriter, r *http.Request) {
+					b, _ := io.ReadAll(r.Body)
+					gotBody = string(b)
+					if stream {
+						writeSSE(w, textStreamEvents(answer, "end_turn")...)
+						return
+					}
+					writeJSON(w, fmt.Sprintf(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":%q}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`, answer))
+				})
+
+				genCfg := *tt.genCfg
+				genCfg.ResponseMIMEType = "application/json"
+				req := &model.LLMRequest{Contents: tt.contents, Config: &genCfg}
+				got := collect(t, m, req, stream)
+
+				if strings.Contains(gotBody, `{"text":"{","type":"text"}`) {
+					t.Errorf("request body = %s, want no assistant prefill", gotBody)
+				}
+				if text := got[len(got)-1].Content.Parts[0].Text; text != answer {
+					t.Errorf("final text = %q, want %q", text, answer)
+				}
+			})
+		}
+	}
+}
+
+func TestGenerate_JSONOutputInvalid(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config:   &genai.GenerateContentConfig{ResponseMIMEType: "application/json"},
+	}
+	for _, err := range m.GenerateContent(t.Context(), req, false) {
+		if err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+			t.Errorf("GenerateContent() error = %v, want invalid JSON error", err)
+		}
+	}
+}
+
+func TestConvertRequest_ToolChoiceNone(t *testing.T) {
+	tools := []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "lookup"}}}}
+	tests := []struct {
+		name       string
+		toolConfig *genai.ToolConfig
+		want       string
+	}{
+		{name: "unset"},
+		{name: "auto", toolConfig: &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeAuto}}},
+		{name: "none", toolConfig: &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeNone}}, want: `{"type":"none"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens}
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Summarize.", "user")},
+				Config:   &genai.GenerateContentConfig{Tools: tools, ToolConfig: tt.toolConfig},
+			}
+			params, err := m.convertRequest(t.Context(), req)
+			if err != nil {
+				t.Fatalf("convertRequest() error = %v", err)
+			}
+
+			if len(params.Tools) != 1 {
+				t.Errorf("len(Tools) = %d, want the tool kept", len(params.Tools))
+			}
+			var got string
+			if params.ToolChoice.OfNone != nil {
+				data, err := json.Marshal(params.ToolChoice)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = string(data)
+			} else if params.ToolChoice.OfAuto != nil || params.ToolChoice.OfAny != nil || params.ToolChoice.OfTool != nil {
+				t.Errorf("ToolChoice = %+v, want unset", params.ToolChoice)
+			}
+			if got != tt.want {
+				t.Errorf("ToolChoice = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRequest_Deterministic(t *testing.T) {
+	props := make(map[string]*genai.Schema)
+	for _, name := range []string{"query", "limit", "offset", "sort", "filters", "fields", "locale", "cursor"} {
+		props[name] = &genai.Schema{Type: "STRING", Description: name}
+	}
+	props["filters"] = &genai.Schema{Type: "OBJECT", Properties: map[string]*genai.Schema{
+		"from": {Type: "STRING", Format: "date-time"},
+		"to":   {Type: "STRING", Format: "date-time"},
+		"tags": {Type: "ARRAY", Items: &genai.Schema{Type: "STRING"}},
+	}}
+	newRequest := func() *model.LLMRequest {
+		return &model.LLMRequest{
+			Contents: []*genai.Content{
+				genai.NewContentFromText("Find recent posts", "user"),
+				{Role: "model", Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "search", Args: map[string]any{"query": "posts", "limit": 10, "sort": "date"}}}}},
+				{Role: "user", Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{ID: "toolu_1", Name: "search", Response: map[string]any{"total": 2, "items": []any{"a", "b"}, "next": "c2"}}}}},
+			},
+			Config: &genai.GenerateContentConfig{
+				SystemInstruction: &genai.Content{Parts: []*genai.Part{{Text: "Be brief."}, NewCacheBreakpointPart(), {Text: "Today is Monday."}}},
+				Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+					{Name: "search", Parameters: &genai.Schema{Type: "OBJECT", Title: "Search", Description: "Search parameters", Properties: props, Required: []string{"query"}}},
+					{Name: "fetch", ParametersJsonSchema: map[string]any{"type": "object", "properties": map[string]any{"id": map[string]any{"type": "string"}, "fields": map[string]any{"type": "array"}}}},
+					{Name: "annotate", ParametersJsonSchema: map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"note": map[string]any{"$ref": "#/$defs/note"}},
+						"$defs": map[string]any{"note": map[string]any{
+							"type":       "object",
+							"properties": map[string]any{"text": map[string]any{"type": "string"}, "author": map[string]any{"type": "string"}},
+						}},
+						"anyOf": []any{map[string]any{"required": []any{"note"}}},
+					}},
+				}}},
+			},
+		}
+	}
+
+	var bodies [][]byte
+	cfg := &Config{
+		CacheTools:        true,
+		StrictToolSchemas: true,
+		MCPServers:        []MCPServerConfig{{URL: "https://mcp.example.com/sse", Name: "example"}},
+	}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		writeJSON(w, okMessage)
+	})
+	ctx := WithContainer(t.Context(), "container_1")
+	for range 20 {
+		for _, err := range m.GenerateContent(ctx, newRequest(), false) {
+			if err != nil {
+				t.Fatalf("GenerateContent() error = %v", err)
+			}
+		}
+	}
+
+	for _, body := range bodies[1:] {
+		if !bytes.Equal(body, bodies[0]) {
+			t.Fatalf("request JSON differs between identical requests:\n%s\n%s", bodies[0], body)
+		}
+	}
+	for _, want := range []string{`"additionalProperties":false,"description":"Search parameters"`, `"title":"Search","type":"object"`, `"$defs":{"note":`, `"container":"container_1","mcp_servers":[`} {
+		if !bytes.Contains(bodies[0], []byte(want)) {
+			t.Errorf("request JSON = %s, want it to contain %s", bodies[0], want)
+		}
+	}
+}
+
+func TestConvertRequest_DefaultSamplingParams(t *testing.T) {
+	cfg := Config{
+		DefaultTemperature: genai.Ptr(0.2),
+		DefaultTopP:        genai.Ptr(0.9),
+		DefaultTopK:        genai.Ptr(40),
+	}
+	tests := []struct {
+		name      string
+		cfg       Config
+		reqConfig *genai.GenerateContentConfig
+		wantTemp  float64
+		wantTopP  float64
+		wantTopK  int64
+		wantUnset bool
+	}{
+		{name: "no_defaults", wantUnset: true},
+		{name: "defaults", cfg: cfg, wantTemp: 0.2, wantTopP: 0.9, wantTopK: 40},
+		{
+			name:      "request_overrides",
+			cfg:       cfg,
+			reqConfig: &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.5), TopK: genai.Ptr[float32](5)},
+			wantTemp:  0.5,
+			wantTopP:  0.9,
+			wantTopK:  5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens, cfg: tt.cfg}
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+				Config:   tt.reqConfig,
+			}
+
+			params, err := m.convertRequest(t.Context(), req)
+			if err != nil {
+				t.Fatalf("convertRequest() error = %v", err)
+			}
+			if tt.wantUnset {
+				if params.Temperature.Valid() || params.TopP.Valid() || params.TopK.Valid() {
+					t.Errorf("sampling params = %v/%v/%v, want unset", params.Temperature, params.TopP, params.TopK)
+				}
+				return
+			}
+			if got := params.Temperature.Value; got != tt.wantTemp {
+				t.Errorf("Temperature = %v, want %v", got, tt.wantTemp)
+			}
+			if got := params.TopP.Value; got != tt.wantTopP {
+				t.Errorf("TopP = %v, want %v", got, tt.wantTopP)
+			}
+			if got := params.TopK.Value; got != tt.wantTopK {
+				t.Errorf("TopK = %v, want %v", got, tt.wantTopK)
+			}
+		})
+	}
+}
+
+func TestConvertRequest_InvalidSamplingParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		reqConfig *genai.GenerateContentConfig
+		wantErr   string
+	}{
+		{name: "temperature", reqConfig: &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](2.5)}, wantErr: "invalid temperature"},
+		{name: "negative_temperature", reqConfig: &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](-0.1)}, wantErr: "invalid temperature"},
+		{name: "top_p", reqConfig: &genai.GenerateContentConfig{TopP: genai.Ptr[float32](1.5)}, wantErr: "invalid top_p"},
+		{name: "top_k", reqConfig: &genai.GenerateContentConfig{TopK: genai.Ptr[float32](-1)}, wantErr: "invalid top_k"},
+		{name: "default_temperature", cfg: Config{DefaultTemperature: genai.Ptr(1.2)}, wantErr: "invalid temperature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens, cfg: tt.cfg}
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+				Config:   tt.reqConfig,
+			}
+			if _, err := m.convertRequest(t.Context(), req); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("convertRequest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConvertRequest_ModelTemperature(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		cfg       Config
+		reqConfig *genai.GenerateContentConfig
+		want      float64
+		wantUnset bool
+	}{
+		{name: "built_in", model: "claude-3-5-haiku-20241022", want: 0.7},
+		{name: "no_entry", model: "claude-sonnet-4-20250514", wantUnset: true},
+		{name: "config_entry", model: "claude-sonnet-4-20250514", cfg: Config{ModelTemperatures: map[string]float64{"claude-sonnet-4": 0.4}}, want: 0.4},
+		{name: "config_overrides_built_in", model: "claude-3-5-haiku-20241022", cfg: Config{ModelTemperatures: map[string]float64{"claude-3-5-haiku": 0.3}}, want: 0.3},
+		{name: "longest_prefix", model: "claude-sonnet-4-5-20250929", cfg: Config{ModelTemperatures: map[string]float64{"claude-sonnet-4": 0.4, "claude-sonnet-4-5": 0.6}}, want: 0.6},
+		{name: "default_temperature", model: "claude-3-5-haiku-20241022", cfg: Config{DefaultTemperature: genai.Ptr(0.2)}, want: 0.2},
+		{name: "request", model: "claude-3-5-haiku-20241022", reqConfig: &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.5)}, want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: anthropic.Model(tt.model), defaultMaxTokens: defaultMaxTokens, cfg: tt.cfg}
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+				Config:   tt.reqConfig,
+			}
+			params, err := m.convertRequest(t.Context(), req)
+			if err != nil {
+				t.Fatalf("convertRequest() error = %v", err)
+			}
+			if tt.wantUnset {
+				if params.Temperature.Valid() {
+					t.Errorf("Temperature = %v, want unset", params.Temperature.Value)
+				}
+				return
+			}
+			if got := params.Temperature.Value; !params.Temperature.Valid() || got != tt.want {
+				t.Errorf("Temperature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertRequest_DefaultStopSequences(t *testing.T) {
+	m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens, cfg: Config{DefaultStopSequences: []string{"###", "END"}}}
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config:   &genai.GenerateContentConfig{StopSequences: []string{"END", "</answer>"}},
+	}
+
+	params, err := m.convertRequest(t.Context(), req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+	want := []string{"###", "END", "</answer>"}
+	if diff := cmp.Diff(want, params.StopSequences); diff != "" {
+		t.Errorf("StopSequences mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeStopSequences_Limit(t *testing.T) {
+	sequences := func(n int) []string {
+		seqs := make([]string, n)
+		for i := range seqs {
+			seqs[i] = fmt.Sprintf("stop-%d", i)
+		}
+		return seqs
+	}
+
+	tests := []struct {
+		name      string
+		defaults  []string
+		requested []string
+		wantLen   int
+		wantErr   bool
+	}{
+		{name: "request_duplicates", requested: []string{"END", "", "END"}, wantLen: 1},
+		{name: "at_limit", requested: sequences(maxStopSequences), wantLen: maxStopSequences},
+		{name: "at_limit_with_duplicates", defaults: sequences(10), requested: sequences(maxStopSequences), wantLen: maxStopSequences},
+		{name: "over_limit", defaults: []string{"extra"}, requested: sequences(maxStopSequences), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeStopSequences(tt.defaults, tt.requested)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeStopSequences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("len(mergeStopSequences()) = %d, want %d", len(got), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestDebugHooks(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			var gotRequest []byte
+			var gotResponse *anthropic.Message
+			cfg := &Config{
+				OnRequest: func(params anthropic.MessageNewParams) {
+					gotRequest, _ = json.Marshal(params)
+				},
+				OnResponse: func(msg *anthropic.Message) {
+					gotResponse = msg
+				},
+			}
+			m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			collect(t, m, req, stream)
+
+			if !strings.Contains(string(gotRequest), `"content":[{"text":"Hi","type":"text"}]`) {
+				t.Errorf("OnRequest params = %s, want user message", gotRequest)
+			}
+			if gotResponse == nil || gotResponse.Content[0].Text != "ok" {
+				t.Errorf("OnResponse message = %+v, want text %q", gotResponse, "ok")
+			}
+		})
+	}
+}
+
+func TestGenerate_ResponseTransform(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			calls := 0
+			cfg := &Config{
+				ResponseTransform: func(resp *model.LLMResponse) *model.LLMResponse {
+					calls++
+					if resp.Partial {
+						t.Error("ResponseTransform called with a partial response")
+					}
+					return &model.LLMResponse{
+						Content:      genai.NewContentFromText(strings.ToUpper(resp.Content.Parts[0].Text), genai.RoleModel),
+						TurnComplete: resp.TurnComplete,
+					}
+				},
+			}
+			m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, stream)
+
+			if calls != 1 {
+				t.Errorf("ResponseTransform called %d times, want 1", calls)
+			}
+			final := got[len(got)-1]
+			if text := final.Content.Parts[0].Text; text != "OK" {
+				t.Errorf("final text = %q, want %q", text, "OK")
+			}
+			for _, resp := range got[:len(got)-1] {
+				if resp.Content != nil && resp.Content.Parts[0].Text == "OK" {
+					t.Errorf("partial response %+v was transformed", resp)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertRequest_UnsupportedParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		reqConfig *genai.GenerateContentConfig
+		wantErr   string
+	}{
+		{name: "seed", reqConfig: &genai.GenerateContentConfig{Seed: genai.Ptr[int32](42)}, wantErr: "Seed"},
+		{name: "penalties", reqConfig: &genai.GenerateContentConfig{PresencePenalty: genai.Ptr[float32](0.5), FrequencyPenalty: genai.Ptr[float32](0.5)}, wantErr: "PresencePenalty, FrequencyPenalty"},
+		{name: "candidate_count", reqConfig: &genai.GenerateContentConfig{CandidateCount: 3}, wantErr: "single candidate per request"},
+		{name: "response_logprobs", reqConfig: &genai.GenerateContentConfig{ResponseLogprobs: true}, wantErr: "does not return token log probabilities"},
+		{name: "logprobs", reqConfig: &genai.GenerateContentConfig{Logprobs: genai.Ptr[int32](5)}, wantErr: "Logprobs are not supported"},
+		{name: "image_modality", reqConfig: &genai.GenerateContentConfig{ResponseModalities: []string{"TEXT", "IMAGE"}}, wantErr: "ResponseModalities"},
+		{name: "supported", reqConfig: &genai.GenerateContentConfig{CandidateCount: 1, ResponseModalities: []string{"TEXT"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens}
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+				Config:   tt.reqConfig,
+			}
+			_, err := m.convertRequest(t.Context(), req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("convertRequest() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("convertRequest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerate_MultipleCandidates(t *testing.T) {
+	called := false
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config:   &genai.GenerateContentConfig{CandidateCount: 3},
+	}
+	for _, stream := range []bool{false, true} {
+		for resp, err := range m.GenerateContent(t.Context(), req, stream) {
+			if err == nil || !strings.Contains(err.Error(), "CandidateCount 3 is not supported") {
+				t.Errorf("GenerateContent(stream=%v) = %v, %v, want CandidateCount error", stream, resp, err)
+			}
+		}
+	}
+	if called {
+		t.Error("request was sent to the API, want it rejected before the call")
+	}
+}
+
+func TestNewModel_ModelNameFromEnv(t *testing.T) {
+	cfg := &Config{APIKey: "test-api-key", Variant: VariantAnthropicAPI}
+
+	t.Run("argument", func(t *testing.T) {
+		t.Setenv("ANTHROPIC_MODEL", "claude-haiku-4-5")
+		m, err := NewModel(t.Context(), "claude-sonnet-4-20250514", cfg)
+		if err != nil {
+			t.Fatalf("NewModel() error = %v", err)
+		}
+		if got := m.Name(); got != "claude-sonnet-4-20250514" {
+			t.Errorf("Name() = %q, want the argument", got)
+		}
+	})
+
+	t.Run("env_fallback", func(t *testing.T) {
+		t.Setenv("ANTHROPIC_MODEL", "claude-haiku-4-5")
+		m, err := NewModel(t.Context(), "", cfg)
+		if err != nil {
+			t.Fatalf("NewModel() error = %v", err)
+		}
+		if got := m.Name(); got != "claude-haiku-4-5" {
+			t.Errorf("Name() = %q, want %q", got, "claude-haiku-4-5")
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Setenv("ANTHROPIC_MODEL", "")
+		_, err := NewModel(t.Context(), "", cfg)
+		if err == nil || !strings.Contains(err.Error(), "model name is required") {
+			t.Fatalf("NewModel() error = %v, want missing model name", err)
+		}
+	})
+}
+
+func TestGenerate_BetaHeaders(t *testing.T) {
+	var gotBeta []string
+	cfg := &Config{
+		InterleavedThinking: true,
+		BetaHeaders:         []string{"context-1m-2025-08-07", " ", interleavedThinkingBeta, "context-1m-2025-08-07"},
+	}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		gotBeta = r.Header.Values("anthropic-beta")
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	collect(t, m, req, false)
+
+	want := []string{interleavedThinkingBeta + ",context-1m-2025-08-07"}
+	if diff := cmp.Diff(want, gotBeta); diff != "" {
+		t.Errorf("anthropic-beta headers mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerate_ServedModel(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+			m.name = "claude-sonnet-4-0"
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, stream)
+
+			if served := got[len(got)-1].CustomMetadata[MetadataKeyModel]; served != "claude-sonnet-4-20250514" {
+				t.Errorf("CustomMetadata[%q] = %v, want %q", MetadataKeyModel, served, "claude-sonnet-4-20250514")
+			}
+			if requested, ok := got[len(got)-1].CustomMetadata[MetadataKeyRequestedModel]; ok {
+				t.Errorf("CustomMetadata[%q] = %v, want unset for an alias", MetadataKeyRequestedModel, requested)
+			}
+		})
+	}
+}
+
+func TestGenerate_ServedModelMismatch(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+			m.name = "claude-opus-4-1"
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, stream)
+
+			final := got[len(got)-1]
+			if requested := final.CustomMetadata[MetadataKeyRequestedModel]; requested != "claude-opus-4-1" {
+				t.Errorf("CustomMetadata[%q] = %v, want %q", MetadataKeyRequestedModel, requested, "claude-opus-4-1")
+			}
+			if served := final.CustomMetadata[MetadataKeyModel]; served != "claude-sonnet-4-20250514" {
+				t.Errorf("CustomMetadata[%q] = %v, want %q", MetadataKeyModel, served, "claude-sonnet-4-20250514")
+			}
+		})
+	}
+}
+
+func TestGenerate_ContainerRoundTrip(t *testing.T) {
+	const container = `{"id":"container_1","expires_at":"2025-06-01T12:00:00Z"}`
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			var gotContainers []any
+			m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				gotContainers = append(gotContainers, body["container"])
+				if stream {
+					events := textStreamEvents("ok", "end_turn")
+					events[4] = `{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null,"container":` + container + `},"usage":{"output_tokens":15}}`
+					writeSSE(w, events...)
+					return
+				}
+				writeJSON(w, strings.Replace(okMessage, `"stop_reason"`, `"container":`+container+`,"stop_reason"`, 1))
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Write a file", "user")}}
+			got := collect(t, m, req, stream)
+			id, _ := got[len(got)-1].CustomMetadata[MetadataKeyContainer].(string)
+			if id != "container_1" {
+				t.Fatalf("CustomMetadata[%q] = %q, want %q", MetadataKeyContainer, id, "container_1")
+			}
+
+			req = &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Read the file", "user")}}
+			for _, err := range m.GenerateContent(WithContainer(t.Context(), id), req, stream) {
+				if err != nil {
+					t.Fatalf("GenerateContent() error = %v", err)
+				}
+			}
+
+			if diff := cmp.Diff([]any{nil, "container_1"}, gotContainers); diff != "" {
+				t.Errorf("request containers mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMaybeAppendUserContent(t *testing.T) {
+	call := &genai.Content{Role: "model", Parts: []*genai.Part{
+		{Text: "Let me check."},
+		{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "lookup"}},
+	}}
+	tests := []struct {
+		name     string
+		contents []*genai.Content
+		wantLen  int
+	}{
+		{name: "empty", wantLen: 1},
+		{name: "ends_with_user", contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}, wantLen: 1},
+		{name: "ends_with_model_text", contents: []*genai.Content{genai.NewContentFromText("Hi", "user"), genai.NewContentFromText("Hello", "model")}, wantLen: 3},
+		{name: "ends_with_function_call", contents: []*genai.Content{genai.NewContentFromText("Hi", "user"), call}, wantLen: 2},
+		{name: "ends_with_tool", contents: []*genai.Content{genai.NewContentFromText("Hi", "user"), call, genai.NewContentFromText("found", "tool")}, wantLen: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{}
+			req := &model.LLMRequest{Contents: tt.contents}
+			m.maybeAppendUserContent(req)
+			if got := len(req.Contents); got != tt.wantLen {
+				t.Errorf("len(Contents) = %d, want %d", got, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestMaybeAppendUserContent_EmptyConversationPrompt(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "default", want: defaultEmptyConversationPrompt},
+		{name: "configured", cfg: Config{EmptyConversationPrompt: "Suis les instructions."}, want: "Suis les instructions."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{cfg: tt.cfg}
+			req := &model.LLMRequest{}
+			m.maybeAppendUserContent(req)
+			want := []*genai.Content{genai.NewContentFromText(tt.want, "user")}
+			if diff := cmp.Diff(want, req.Contents); diff != "" {
+				t.Errorf("Contents mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMaybeAppendUserContent_Disabled(t *testing.T) {
+	m := &anthropicModel{cfg: Config{DisableAutoUserContent: true}}
+	for _, contents := range [][]*genai.Content{
+		nil,
+		{genai.NewContentFromText("Hi", "user"), genai.NewContentFromText("Hello", "model")},
+	} {
+		req := &model.LLMRequest{Contents: slices.Clone(contents)}
+		m.maybeAppendUserContent(req)
+		if diff := cmp.Diff(contents, req.Contents); diff != "" {
+			t.Errorf("Contents mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestClientProvider(t *testing.T) {
+	var gotPath, gotKey string
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("X-Api-Key")
+		writeJSON(w, `{"id":"claude-sonnet-4-20250514","type":"model","display_name":"Claude Sonnet 4","created_at":"2025-05-22T00:00:00Z"}`)
+	})
+
+	var llm model.LLM = m
+	client := llm.(ClientProvider).Client()
+	info, err := client.Models.Get(t.Context(), "claude-sonnet-4-20250514", anthropic.ModelGetParams{})
+	if err != nil {
+		t.Fatalf("Models.Get() error = %v", err)
+	}
+	if info.DisplayName != "Claude Sonnet 4" {
+		t.Errorf("DisplayName = %q, want %q", info.DisplayName, "Claude Sonnet 4")
+	}
+	if gotPath != "/v1/models/claude-sonnet-4-20250514" || gotKey != "test-api-key" {
+		t.Errorf("request to %q with key %q, want the model's endpoint and credentials", gotPath, gotKey)
+	}
+}
+
+func TestGenerate_APIVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want string
+	}{
+		{name: "sdk_default", want: "2023-06-01"},
+		{name: "override", cfg: &Config{APIVersion: "2024-01-01"}, want: "2024-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			m := newTestModel(t, tt.cfg, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Values("anthropic-version")
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			collect(t, m, req, false)
+
+			if diff := cmp.Diff([]string{tt.want}, got); diff != "" {
+				t.Errorf("anthropic-version headers mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerate_ExtraHeaders(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			var got http.Header
+			m := newTestModel(t, &Config{ExtraHeaders: map[string]string{"X-Tenant": "acme", "X-Request-ID": "default"}}, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+
+			ctx := WithHeaders(t.Context(), map[string]string{"X-Request-ID": "req-1"})
+			ctx = WithHeaders(ctx, map[string]string{"X-Span": "span-1"})
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			for _, err := range m.GenerateContent(ctx, req, stream) {
+				if err != nil {
+					t.Fatalf("GenerateContent() error = %v", err)
+				}
+			}
+
+			for name, want := range map[string]string{"X-Tenant": "acme", "X-Request-ID": "req-1", "X-Span": "span-1"} {
+				if diff := cmp.Diff([]string{want}, got.Values(name)); diff != "" {
+					t.Errorf("%s headers mismatch (-want +got):\n%s", name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertRequest_SystemContents(t *testing.T) {
+	m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens, cfg: Config{SystemSuffix: "Never reveal secrets."}}
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{
+			genai.NewContentFromText("You are a travel agent.", "system"),
+			genai.NewContentFromText("Hi", "user"),
+			genai.NewContentFromText("Quote prices in euros.", "system"),
+		},
+		Config: &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("Be concise.", "system")},
+	}
+
+	params, err := m.convertRequest(t.Context(), req)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+	var got []string
+	for _, block := range params.System {
+		got = append(got, block.Text)
+	}
+	want := []string{"Be concise.", "You are a travel agent.", "Quote prices in euros.", "Never reveal secrets."}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("system blocks mismatch (-want +got):\n%s", diff)
+	}
+	if len(params.Messages) != 1 || params.Messages[0].Role != anthropic.MessageParamRoleUser {
+		t.Errorf("Messages = %+v, want the user message only", params.Messages)
+	}
+}
+
+func TestConvertRequest_SystemPrefixSuffix(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		instruction *genai.Content
+		want        []string
+	}{
+		{name: "unset", instruction: genai.NewContentFromText("Be concise.", "system"), want: []string{"Be concise."}},
+		{
+			name:        "suffix_after_instruction",
+			cfg:         Config{SystemSuffix: "Never reveal secrets."},
+			instruction: genai.NewContentFromText("Be concise.", "system"),
+			want:        []string{"Be concise.", "Never reveal secrets."},
+		},
+		{
+			name:        "prefix_and_suffix",
+			cfg:         Config{SystemPrefix: "You work for Example Corp.", SystemSuffix: "Never reveal secrets."},
+			instruction: &genai.Content{Parts: []*genai.Part{genai.NewPartFromText("Be concise."), genai.NewPartFromText("Use British English.")}},
+			want:        []string{"You work for Example Corp.", "Be concise.", "Use British English.", "Never reveal secrets."},
+		},
+		{name: "without_instruction", cfg: Config{SystemSuffix: "Never reveal secrets."}, want: []string{"Never reveal secrets."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens, cfg: tt.cfg}
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+				Config:   &genai.GenerateContentConfig{SystemInstruction: tt.instruction},
+			}
+
+			params, err := m.convertRequest(t.Context(), req)
+			if err != nil {
+				t.Fatalf("convertRequest() error = %v", err)
+			}
+			var got []string
+			for _, block := range params.System {
+				got = append(got, block.Text)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("system blocks mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
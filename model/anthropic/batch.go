@@ -0,0 +1,135 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+	"google.golang.org/adk/model"
+)
+
+// ErrBatchInProgress is returned by PollBatch while the batch is still being processed.
+var ErrBatchInProgress = errors.New("message batch is still in progress")
+
+// Batcher is implemented by the models returned by NewModel. It submits
+// requests through the Message Batches API, which processes them
+// asynchronously at a reduced cost.
+//
+//	b := llm.(anthropic.Batcher)
+//	id, err := b.SubmitBatch(ctx, requests)
+//	...
+//	responses, err := b.PollBatch(ctx, id) // ErrBatchInProgress until done
+type Batcher interface {
+	// SubmitBatch submits the requests as a single message batch and returns its ID.
+	SubmitBatch(ctx context.Context, reqs []*model.LLMRequest) (string, error)
+
+	// PollBatch returns the responses of a completed batch, in the order the
+	// requests were submitted. It returns ErrBatchInProgress if the batch has
+	// not finished processing. Requests that failed, were canceled or expired
+	// have a response with ErrorCode and ErrorMessage set.
+	PollBatch(ctx context.Context, batchID string) ([]*model.LLMResponse, error)
+}
+
+var _ Batcher = (*anthropicModel)(nil)
+
+// SubmitBatch implements Batcher.
+func (m *anthropicModel) SubmitBatch(ctx context.Context, reqs []*model.LLMRequest) (string, error) {
+	if len(reqs) == 0 {
+		return "", fmt.Errorf("no requests to submit")
+	}
+
+	batch := make([]anthropic.MessageBatchNewParamsRequest, 0, len(reqs))
+	for i, req := range reqs {
+		if wantsJSON(req) {
+			return "", fmt.Errorf("request %d: JSON output is not supported in message batches", i)
+		}
+		m.maybeAppendUserContent(req)
+		params, err := m.convertRequest(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert request %d: %w", i, err)
+		}
+		// The batch request params mirror the Messages API params
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal request %d: %w", i, err)
+		}
+		batch = append(batch, anthropic.MessageBatchNewParamsRequest{
+			CustomID: strconv.Itoa(i),
+			Params:   param.Override[anthropic.MessageBatchNewParamsRequestParams](json.RawMessage(raw)),
+		})
+	}
+
+	res, err := m.client.Messages.Batches.New(ctx, anthropic.MessageBatchNewParams{Requests: batch})
+	if err != nil {
+		return "", fmt.Errorf("failed to submit batch: %w", err)
+	}
+	return res.ID, nil
+}
+
+// PollBatch implements Batcher.
+func (m *anthropicModel) PollBatch(ctx context.Context, batchID string) ([]*model.LLMResponse, error) {
+	batch, err := m.client.Messages.Batches.Get(ctx, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch %s: %w", batchID, err)
+	}
+	if batch.ProcessingStatus != anthropic.MessageBatchProcessingStatusEnded {
+		return nil, ErrBatchInProgress
+	}
+
+	counts := batch.RequestCounts
+	responses := make([]*model.LLMResponse, counts.Succeeded+counts.Errored+counts.Canceled+counts.Expired)
+
+	stream := m.client.Messages.Batches.ResultsStreaming(ctx, batchID)
+	defer stream.Close()
+	for stream.Next() {
+		result := stream.Current()
+		i, err := strconv.Atoi(result.CustomID)
+		if err != nil || i < 0 || i >= len(responses) {
+			return nil, fmt.Errorf("unexpected custom_id %q in batch %s", result.CustomID, batchID)
+		}
+
+		switch result.Result.Type {
+		case "succeeded":
+			resp, err := converters.MessageToLLMResponse(&result.Result.Message)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert response %d: %w", i, err)
+			}
+			responses[i] = resp
+		case "errored":
+			responses[i] = &model.LLMResponse{
+				ErrorCode:    string(result.Result.Error.Error.Type),
+				ErrorMessage: result.Result.Error.Error.Message,
+			}
+		default:
+			// Canceled or expired before processing
+			responses[i] = &model.LLMResponse{
+				ErrorCode:    result.Result.Type,
+				ErrorMessage: fmt.Sprintf("request was %s before it was processed", result.Result.Type),
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read results of batch %s: %w", batchID, err)
+	}
+	return responses, nil
+}
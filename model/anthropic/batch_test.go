@@ -0,0 +1,94 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// batchJSON returns a message batch object with the given processing status.
+func batchJSON(status string) string {
+	return fmt.Sprintf(`{"id":"msgbatch_1","type":"message_batch","processing_status":%q,"request_counts":{"processing":0,"succeeded":1,"errored":1,"canceled":0,"expired":0},"created_at":"2025-01-01T00:00:00Z","expires_at":"2025-01-02T00:00:00Z","archived_at":null,"cancel_initiated_at":null,"ended_at":null,"results_url":null}`, status)
+}
+
+func TestBatch(t *testing.T) {
+	var submitted string
+	status := "in_progress"
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/batches":
+			b, _ := io.ReadAll(r.Body)
+			submitted = string(b)
+			writeJSON(w, batchJSON("in_progress"))
+		case r.URL.Path == "/v1/messages/batches/msgbatch_1":
+			writeJSON(w, batchJSON(status))
+		case r.URL.Path == "/v1/messages/batches/msgbatch_1/results":
+			w.Header().Set("Content-Type", "application/x-jsonl")
+			// Results are not in request order
+			fmt.Fprintln(w, `{"custom_id":"1","result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"bad request"}}}}`)
+			fmt.Fprintf(w, `{"custom_id":"0","result":{"type":"succeeded","message":%s}}`+"\n", okMessage)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	reqs := []*model.LLMRequest{
+		{Contents: []*genai.Content{genai.NewContentFromText("First", "user")}},
+		{Contents: []*genai.Content{genai.NewContentFromText("Second", "user")}},
+	}
+	b := model.LLM(m).(Batcher)
+
+	id, err := b.SubmitBatch(t.Context(), reqs)
+	if err != nil {
+		t.Fatalf("SubmitBatch() error = %v", err)
+	}
+	if id != "msgbatch_1" {
+		t.Errorf("SubmitBatch() = %q, want %q", id, "msgbatch_1")
+	}
+	for i, text := range []string{"First", "Second"} {
+		want := fmt.Sprintf(`{"custom_id":"%d","params":{"max_tokens":4096,"messages":[{"content":[{"text":%q,"type":"text"}],"role":"user"}]`, i, text)
+		if !strings.Contains(submitted, want) {
+			t.Errorf("submitted batch = %s, want request %s", submitted, want)
+		}
+	}
+
+	if _, err := b.PollBatch(t.Context(), id); !errors.Is(err, ErrBatchInProgress) {
+		t.Fatalf("PollBatch() error = %v, want %v", err, ErrBatchInProgress)
+	}
+
+	status = "ended"
+	got, err := b.PollBatch(t.Context(), id)
+	if err != nil {
+		t.Fatalf("PollBatch() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("PollBatch() returned %d responses, want 2", len(got))
+	}
+	if text := got[0].Content.Parts[0].Text; text != "ok" {
+		t.Errorf("responses[0] text = %q, want %q", text, "ok")
+	}
+	if got[1].ErrorCode != "invalid_request_error" || got[1].ErrorMessage != "bad request" {
+		t.Errorf("responses[1] error = %q: %q, want invalid_request_error: bad request", got[1].ErrorCode, got[1].ErrorMessage)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+)
+
+// cacheTools places a cache breakpoint on the last tool that accepts one, so
+// that all tool definitions up to it are cached. Only the computer use tool,
+// which is sent as a raw definition, does not accept one; it is small enough
+// to be left out of the cached prefix.
+func cacheTools(tools []anthropic.ToolUnionParam) {
+	for i := len(tools) - 1; i >= 0; i-- {
+		if cc := tools[i].GetCacheControl(); cc != nil {
+			*cc = anthropic.NewCacheControlEphemeralParam()
+			return
+		}
+	}
+}
+
+// setCacheTTL sets the time to live of the cache breakpoints of the tools and
+// system blocks of params.
+func setCacheTTL(params *anthropic.MessageNewParams, ttl anthropic.CacheControlEphemeralTTL) {
+	for _, tool := range params.Tools {
+		if cc := tool.GetCacheControl(); cc != nil && cc.Type != "" {
+			cc.TTL = ttl
+		}
+	}
+	for i := range params.System {
+		if params.System[i].CacheControl.Type != "" {
+			params.System[i].CacheControl.TTL = ttl
+		}
+	}
+}
+
+// checkCacheBreakpoints returns an error if params place more cache
+// breakpoints than the API accepts.
+func checkCacheBreakpoints(params *anthropic.MessageNewParams) error {
+	n := 0
+	for _, tool := range params.Tools {
+		if cc := tool.GetCacheControl(); cc != nil && cc.Type != "" {
+			n++
+		}
+	}
+	for _, block := range params.System {
+		if block.CacheControl.Type != "" {
+			n++
+		}
+	}
+	if n > converters.MaxCacheBreakpoints {
+		return fmt.Errorf("too many cache breakpoints: got %d, maximum is %d", n, converters.MaxCacheBreakpoints)
+	}
+	return nil
+}
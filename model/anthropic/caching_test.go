@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestGenerate_SystemCacheBreakpoint(t *testing.T) {
+	var gotBody string
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: &genai.Content{Parts: []*genai.Part{
+				genai.NewPartFromText("Static preamble."),
+				NewCacheBreakpointPart(),
+				genai.NewPartFromText("Dynamic instructions."),
+			}},
+		},
+	}
+	collect(t, m, req, false)
+
+	want := `"system":[{"text":"Static preamble.","cache_control":{"type":"ephemeral"},"type":"text"},{"text":"Dynamic instructions.","type":"text"}]`
+	if !strings.Contains(gotBody, want) {
+		t.Errorf("request body = %s, want %s", gotBody, want)
+	}
+}
+
+func TestConvertRequest_TooManyCacheBreakpoints(t *testing.T) {
+	var parts []*genai.Part
+	for range 5 {
+		parts = append(parts, genai.NewPartFromText("block"), NewCacheBreakpointPart())
+	}
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config:   &genai.GenerateContentConfig{SystemInstruction: &genai.Content{Parts: parts}},
+	}
+
+	m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens}
+	_, err := m.convertRequest(t.Context(), req)
+	if err == nil || !strings.Contains(err.Error(), "too many cache breakpoints: got 5, maximum is 4") {
+		t.Errorf("convertRequest() error = %v, want too many cache breakpoints", err)
+	}
+}
+
+func TestGenerate_CacheTools(t *testing.T) {
+	var gotBody string
+	m := newTestModel(t, &Config{CacheTools: true}, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		writeJSON(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15,"cache_read_input_tokens":1200}}`)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config: &genai.GenerateContentConfig{
+			Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+				{Name: "first", Description: "First tool"},
+				{Name: "second", Description: "Second tool"},
+			}}},
+		},
+	}
+	got := collect(t, m, req, false)
+
+	if n := strings.Count(gotBody, `"cache_control"`); n != 1 {
+		t.Errorf("request body has %d cache_control entries, want 1: %s", n, gotBody)
+	}
+	if !strings.Contains(gotBody, `"description":"Second tool","cache_control":{"type":"ephemeral"}}]`) {
+		t.Errorf("request body = %s, want cache_control on the last tool", gotBody)
+	}
+	if n := got[0].UsageMetadata.CachedContentTokenCount; n != 1200 {
+		t.Errorf("CachedContentTokenCount = %d, want 1200", n)
+	}
+}
+
+func TestConvertRequest_CacheToolsCountsTowardLimit(t *testing.T) {
+	var parts []*genai.Part
+	for range 4 {
+		parts = append(parts, genai.NewPartFromText("block"), NewCacheBreakpointPart())
+	}
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: &genai.Content{Parts: parts},
+			Tools:             []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "lookup"}}}},
+		},
+	}
+
+	m := &anthropicModel{name: "claude-sonnet-4-20250514", defaultMaxTokens: defaultMaxTokens, cfg: Config{CacheTools: true}}
+	_, err := m.convertRequest(t.Context(), req)
+	if err == nil || !strings.Contains(err.Error(), "got 5, maximum is 4") {
+		t.Errorf("convertRequest() error = %v, want too many cache breakpoints", err)
+	}
+}
+
+func TestGenerate_CacheTTL(t *testing.T) {
+	var gotBody string
+	m := newTestModel(t, &Config{CacheTools: true, CacheTTL: CacheTTL1Hour}, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: &genai.Content{Parts: []*genai.Part{
+				genai.NewPartFromText("Static preamble."),
+				NewCacheBreakpointPart(),
+			}},
+			Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "lookup"}}}},
+		},
+	}
+	collect(t, m, req, false)
+
+	want := `"cache_control":{"ttl":"1h","type":"ephemeral"}`
+	if got := strings.Count(gotBody, want); got != 2 {
+		t.Errorf("request body = %s, want %s on the system block and the tool", gotBody, want)
+	}
+}
+
+func TestNewModel_InvalidCacheTTL(t *testing.T) {
+	_, err := NewModel(t.Context(), "claude-sonnet-4-20250514", &Config{APIKey: "test-api-key", CacheTTL: "1d"})
+	if err == nil || !strings.Contains(err.Error(), `invalid CacheTTL "1d"`) {
+		t.Errorf("NewModel() error = %v, want invalid CacheTTL", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// clientKey identifies the settings that determine how a client behaves.
+// Models whose configurations produce the same key share a client.
+type clientKey struct {
+	variant string
+	// Direct API credentials and endpoint
+	apiKey    string
+	authToken string
+	baseURL   string
+	// Vertex AI project and region
+	projectID string
+	region    string
+	// Client options derived from the Config
+	betas      string
+	apiVersion string
+	headers    string
+	noRetry    bool
+	recordDir  string
+	replayDir  string
+}
+
+// clientCache holds the clients shared between models.
+var clientCache = struct {
+	sync.Mutex
+	clients map[clientKey]*anthropic.Client
+}{clients: make(map[clientKey]*anthropic.Client)}
+
+// newClientKey returns the cache key of the client for cfg on variant,
+// resolving settings that fall back to environment variables.
+func newClientKey(cfg *Config, variant string) clientKey {
+	key := clientKey{
+		variant:    variant,
+		betas:      strings.Join(betaHeaders(cfg), ","),
+		apiVersion: cfg.APIVersion,
+		headers:    headersKey(cfg.ExtraHeaders),
+		noRetry:    cfg.RetryPolicy != nil,
+		recordDir:  cfg.RecordDir,
+		replayDir:  cfg.ReplayDir,
+	}
+	if variant == VariantVertexAI {
+		key.projectID = cmp.Or(cfg.VertexProjectID, os.Getenv("GOOGLE_CLOUD_PROJECT"))
+		key.region = cmp.Or(cfg.VertexRegion, os.Getenv("GOOGLE_CLOUD_REGION"))
+		return key
+	}
+	key.apiKey = cmp.Or(cfg.APIKey, os.Getenv("ANTHROPIC_API_KEY"))
+	key.authToken = os.Getenv("ANTHROPIC_AUTH_TOKEN")
+	key.baseURL = os.Getenv("ANTHROPIC_BASE_URL")
+	return key
+}
+
+// sharedClient returns the cached client for key, creating it with newClient
+// on first use. If sharing is disabled in cfg, it always creates a new client.
+// A client that fails to be created is not cached.
+func sharedClient(cfg *Config, key clientKey, newClient func() (anthropic.Client, error)) (*anthropic.Client, error) {
+	if cfg.DisableClientSharing {
+		client, err := newClient()
+		if err != nil {
+			return nil, err
+		}
+		return &client, nil
+	}
+
+	clientCache.Lock()
+	defer clientCache.Unlock()
+	if client, ok := clientCache.clients[key]; ok {
+		return client, nil
+	}
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+	clientCache.clients[key] = &client
+	return &client, nil
+}
+
+// headersKey returns a canonical string form of headers for use in a
+// clientKey.
+func headersKey(headers map[string]string) string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		fmt.Fprintf(&b, "%s: %s\n", name, headers[name])
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"testing"
+)
+
+func TestNewModel_SharedClient(t *testing.T) {
+	newClient := func(cfg *Config) any {
+		t.Helper()
+		m, err := NewModel(t.Context(), "claude-sonnet-4-20250514", cfg)
+		if err != nil {
+			t.Fatalf("NewModel() error = %v", err)
+		}
+		return m.(*anthropicModel).client
+	}
+
+	shared := newClient(&Config{APIKey: "key-shared", Variant: VariantAnthropicAPI})
+	if got := newClient(&Config{APIKey: "key-shared", Variant: VariantAnthropicAPI, DefaultMaxTokens: 1024}); got != shared {
+		t.Error("models with the same credentials do not share a client")
+	}
+	if got := newClient(&Config{APIKey: "key-other", Variant: VariantAnthropicAPI}); got == shared {
+		t.Error("models with different API keys share a client")
+	}
+	if got := newClient(&Config{APIKey: "key-shared", Variant: VariantAnthropicAPI, RetryPolicy: &RetryPolicy{}}); got == shared {
+		t.Error("models with different client options share a client")
+	}
+	if got := newClient(&Config{APIKey: "key-shared", Variant: VariantAnthropicAPI, DisableClientSharing: true}); got == shared {
+		t.Error("model with DisableClientSharing uses the shared client")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+)
+
+// codeExecutionBeta is the beta flag required by the code execution tool.
+const codeExecutionBeta = anthropic.AnthropicBetaCodeExecution2025_05_22
+
+// CodeExecutionToolName is the name of the code execution tool
+// (code_execution_20250522).
+const CodeExecutionToolName = "code_execution"
+
+// codeExecutionTool returns the code execution tool definition, which the SDK
+// does not model.
+func codeExecutionTool() anthropic.ToolUnionParam {
+	return param.Override[anthropic.ToolUnionParam](map[string]any{
+		"type": "code_execution_20250522",
+		"name": CodeExecutionToolName,
+	})
+}
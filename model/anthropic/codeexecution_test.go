@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestCodeExecutionTool(t *testing.T) {
+	const (
+		toolUse    = `{"type":"server_tool_use","id":"srvtoolu_1","name":"code_execution","input":{"code":"print(6 * 7)"}}`
+		toolResult = `{"type":"code_execution_tool_result","tool_use_id":"srvtoolu_1","content":{"type":"code_execution_result","stdout":"42\n","stderr":"","return_code":0,"content":[]}}`
+	)
+	message := `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[` +
+		toolUse + `,` + toolResult + `,{"type":"text","text":"The answer is 42."}],` +
+		`"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`
+	streamEvents := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"server_tool_use","id":"srvtoolu_1","name":"code_execution","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"code\":\"print(6 * 7)\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":` + toolResult + `}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"The answer is 42."}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":15}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			var bodies []string
+			var gotBeta string
+			m := newTestModel(t, &Config{CodeExecutionTool: true}, func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				gotBeta = r.Header.Get("anthropic-beta")
+				if stream {
+					writeSSE(w, streamEvents...)
+					return
+				}
+				writeJSON(w, message)
+			})
+
+			question := genai.NewContentFromText("What is 6 times 7?", "user")
+			got := collect(t, m, &model.LLMRequest{Contents: []*genai.Content{question}}, stream)
+
+			if gotBeta != codeExecutionBeta {
+				t.Errorf("anthropic-beta = %q, want %q", gotBeta, codeExecutionBeta)
+			}
+			if want := `"tools":[{"name":"code_execution","type":"code_execution_20250522"}]`; !strings.Contains(bodies[0], want) {
+				t.Errorf("request body = %s, want %s", bodies[0], want)
+			}
+			final := got[len(got)-1]
+			if n := len(final.Content.Parts); n != 3 {
+				t.Fatalf("final response has %d parts, want 3", n)
+			}
+			for i, part := range final.Content.Parts[:2] {
+				if part.FunctionCall != nil || part.InlineData == nil || part.InlineData.MIMEType != ServerToolBlockMIMEType {
+					t.Errorf("part %d = %+v, want a server tool block", i, part)
+				}
+			}
+
+			// The blocks are sent back as they were received
+			contents := []*genai.Content{question, final.Content, genai.NewContentFromText("Thanks", "user")}
+			collect(t, m, &model.LLMRequest{Contents: contents}, stream)
+			var body struct {
+				Messages []struct {
+					Content []json.RawMessage `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.Unmarshal([]byte(bodies[1]), &body); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			for i, want := range []string{toolUse, toolResult} {
+				var gotBlock, wantBlock any
+				_ = json.Unmarshal(body.Messages[1].Content[i], &gotBlock)
+				_ = json.Unmarshal([]byte(want), &wantBlock)
+				if diff := cmp.Diff(wantBlock, gotBlock); diff != "" {
+					t.Errorf("content[%d] mismatch (-want +got):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
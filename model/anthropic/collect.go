@@ -0,0 +1,105 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"iter"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// CollectStream consumes a stream of responses, such as returned by
+// GenerateContent with streaming enabled, and returns a single response
+// holding the whole turn, for callers that stream to observe progress but
+// want one consolidated response at the end.
+//
+// The final response of the stream, which holds the complete content
+// including function calls, is returned as is. If the stream ends without
+// one, the content of the partial responses is accumulated instead: adjacent
+// text and thought deltas are concatenated, thought signatures are attached to
+// the thought they close, other parts are kept in order, and the latest usage
+// metadata and finish reason are kept. The first error of the stream is
+// returned.
+func CollectStream(stream iter.Seq2[*model.LLMResponse, error]) (*model.LLMResponse, error) {
+	collected := &model.LLMResponse{TurnComplete: true}
+	for resp, err := range stream {
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			continue
+		}
+		if !resp.Partial {
+			return resp, nil
+		}
+		if resp.Content != nil {
+			if collected.Content == nil {
+				collected.Content = &genai.Content{Role: resp.Content.Role}
+			}
+			for _, part := range resp.Content.Parts {
+				collected.Content.Parts = appendStreamedPart(collected.Content.Parts, part)
+			}
+		}
+		if resp.UsageMetadata != nil {
+			collected.UsageMetadata = resp.UsageMetadata
+		}
+		if resp.FinishReason != "" {
+			collected.FinishReason = resp.FinishReason
+		}
+		if resp.CitationMetadata != nil {
+			if collected.CitationMetadata == nil {
+				collected.CitationMetadata = &genai.CitationMetadata{}
+			}
+			collected.CitationMetadata.Citations = append(collected.CitationMetadata.Citations, resp.CitationMetadata.Citations...)
+		}
+		for key, value := range resp.CustomMetadata {
+			if collected.CustomMetadata == nil {
+				collected.CustomMetadata = make(map[string]any)
+			}
+			collected.CustomMetadata[key] = value
+		}
+		if resp.ErrorCode != "" {
+			collected.ErrorCode, collected.ErrorMessage = resp.ErrorCode, resp.ErrorMessage
+		}
+	}
+	return collected, nil
+}
+
+// appendStreamedPart appends the streamed part to parts, concatenating it to
+// the last part if both are text of the same kind, thought or not, and the
+// last thought is not signed yet.
+func appendStreamedPart(parts []*genai.Part, part *genai.Part) []*genai.Part {
+	if part == nil {
+		return parts
+	}
+	isText := func(p *genai.Part) bool {
+		return p.FunctionCall == nil && p.FunctionResponse == nil && p.InlineData == nil && p.FileData == nil &&
+			p.ExecutableCode == nil && p.CodeExecutionResult == nil
+	}
+	if len(parts) > 0 {
+		last := parts[len(parts)-1]
+		if isText(last) && isText(part) && last.Thought == part.Thought && len(last.ThoughtSignature) == 0 {
+			merged := *last
+			merged.Text += part.Text
+			merged.ThoughtSignature = part.ThoughtSignature
+			parts[len(parts)-1] = &merged
+			return parts
+		}
+	}
+	copied := *part
+	return append(parts, &copied)
+}
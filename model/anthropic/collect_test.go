@@ -0,0 +1,113 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestCollectStream(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, interleavedThinkingEvents...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got, err := CollectStream(m.GenerateContent(t.Context(), req, true))
+	if err != nil {
+		t.Fatalf("CollectStream() error = %v", err)
+	}
+
+	want := []*genai.Part{
+		{Text: "I should look it up.", Thought: true, ThoughtSignature: []byte("sig1")},
+		{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "lookup", Args: map[string]any{"q": "x"}}},
+		{Text: "Now answer.", Thought: true, ThoughtSignature: []byte("sig2")},
+		{Text: "Done."},
+	}
+	if diff := cmp.Diff(want, got.Content.Parts); diff != "" {
+		t.Errorf("parts mismatch (-want +got):\n%s", diff)
+	}
+	if got.Partial || !got.TurnComplete {
+		t.Errorf("Partial, TurnComplete = %v, %v, want false, true", got.Partial, got.TurnComplete)
+	}
+	// 15 output tokens, of which about 8 were spent thinking
+	if got.UsageMetadata == nil || got.UsageMetadata.PromptTokenCount != 25 || got.UsageMetadata.CandidatesTokenCount+got.UsageMetadata.ThoughtsTokenCount != 15 {
+		t.Errorf("UsageMetadata = %+v, want 25 prompt and 15 candidates and thoughts tokens", got.UsageMetadata)
+	}
+}
+
+func TestCollectStream_PartialsOnly(t *testing.T) {
+	partial := func(parts ...*genai.Part) *model.LLMResponse {
+		return &model.LLMResponse{Content: &genai.Content{Role: genai.RoleModel, Parts: parts}, Partial: true}
+	}
+	call := &genai.Part{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "lookup"}}
+	responses := []*model.LLMResponse{
+		{UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 25}, Partial: true},
+		partial(&genai.Part{Text: "I should ", Thought: true}),
+		partial(&genai.Part{Text: "look it up.", Thought: true}),
+		partial(&genai.Part{Thought: true, ThoughtSignature: []byte("sig1")}),
+		partial(&genai.Part{Text: "Let me "}),
+		partial(&genai.Part{Text: "check."}),
+		partial(call),
+		{FinishReason: genai.FinishReasonStop, UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 25, CandidatesTokenCount: 15}, Partial: true},
+	}
+	stream := func(yield func(*model.LLMResponse, error) bool) {
+		for _, resp := range responses {
+			if !yield(resp, nil) {
+				return
+			}
+		}
+	}
+
+	got, err := CollectStream(stream)
+	if err != nil {
+		t.Fatalf("CollectStream() error = %v", err)
+	}
+	want := &model.LLMResponse{
+		Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+			{Text: "I should look it up.", Thought: true, ThoughtSignature: []byte("sig1")},
+			{Text: "Let me check."},
+			call,
+		}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 25, CandidatesTokenCount: 15},
+		FinishReason:  genai.FinishReasonStop,
+		TurnComplete:  true,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CollectStream() mismatch (-want +got):\n%s", diff)
+	}
+	if responses[1].Content.Parts[0].Text != "I should " {
+		t.Error("CollectStream() modified a streamed part")
+	}
+}
+
+func TestCollectStream_Error(t *testing.T) {
+	errStream := errors.New("stream failed")
+	stream := func(yield func(*model.LLMResponse, error) bool) {
+		if yield(&model.LLMResponse{Content: genai.NewContentFromText("Hel", genai.RoleModel), Partial: true}, nil) {
+			yield(nil, errStream)
+		}
+	}
+
+	if _, err := CollectStream(stream); !errors.Is(err, errStream) {
+		t.Errorf("CollectStream() error = %v, want %v", err, errStream)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"google.golang.org/genai"
+)
+
+// ComputerUseToolName is the name of Claude's computer-use tool. Calls to the
+// tool are reported as genai.FunctionCall parts with this name.
+const ComputerUseToolName = "computer"
+
+// computerUseBeta is the beta flag required by the computer_20250124 tool and
+// the bash_20250124 and text_editor_20250124 tools.
+const computerUseBeta = anthropic.AnthropicBetaComputerUse2025_01_24
+
+// ComputerUse configures Claude's computer-use tool (computer_20250124).
+//
+// When set in [Config], the tool is added to every request, and any function
+// declaration named [ComputerUseToolName] is replaced by the built-in tool
+// definition. Register a function tool with that name to execute the actions,
+// using [ParseComputerAction] to decode its arguments.
+type ComputerUse struct {
+	// DisplayWidthPx is the width of the display in pixels. Required.
+	DisplayWidthPx int
+	// DisplayHeightPx is the height of the display in pixels. Required.
+	DisplayHeightPx int
+	// DisplayNumber is the X11 display number. Optional.
+	DisplayNumber int
+}
+
+// validate checks that the display dimensions are set.
+func (c *ComputerUse) validate() error {
+	if c.DisplayWidthPx <= 0 || c.DisplayHeightPx <= 0 {
+		return fmt.Errorf("invalid ComputerUse display size %dx%d: width and height must be positive", c.DisplayWidthPx, c.DisplayHeightPx)
+	}
+	return nil
+}
+
+// tool returns the computer-use tool definition.
+func (c *ComputerUse) tool() anthropic.ToolUnionParam {
+	def := map[string]any{
+		"type":              "computer_20250124",
+		"name":              ComputerUseToolName,
+		"display_width_px":  c.DisplayWidthPx,
+		"display_height_px": c.DisplayHeightPx,
+	}
+	if c.DisplayNumber > 0 {
+		def["display_number"] = c.DisplayNumber
+	}
+	return param.Override[anthropic.ToolUnionParam](def)
+}
+
+// Computer-use actions reported in [ComputerAction.Action].
+const (
+	ComputerActionScreenshot     = "screenshot"
+	ComputerActionKey            = "key"
+	ComputerActionHoldKey        = "hold_key"
+	ComputerActionType           = "type"
+	ComputerActionCursorPosition = "cursor_position"
+	ComputerActionMouseMove      = "mouse_move"
+	ComputerActionLeftMouseDown  = "left_mouse_down"
+	ComputerActionLeftMouseUp    = "left_mouse_up"
+	ComputerActionLeftClick      = "left_click"
+	ComputerActionLeftClickDrag  = "left_click_drag"
+	ComputerActionRightClick     = "right_click"
+	ComputerActionMiddleClick    = "middle_click"
+	ComputerActionDoubleClick    = "double_click"
+	ComputerActionTripleClick    = "triple_click"
+	ComputerActionScroll         = "scroll"
+	ComputerActionWait           = "wait"
+)
+
+// ComputerAction is an action requested through the computer-use tool.
+type ComputerAction struct {
+	// Action is the action to perform, such as ComputerActionLeftClick.
+	Action string `json:"action"`
+	// Coordinate is the [x, y] target of mouse actions.
+	Coordinate []int `json:"coordinate,omitempty"`
+	// StartCoordinate is the [x, y] start of a left_click_drag.
+	StartCoordinate []int `json:"start_coordinate,omitempty"`
+	// Text is the text to type, or the key combination for key actions.
+	Text string `json:"text,omitempty"`
+	// ScrollDirection is "up", "down", "left" or "right" for scroll actions.
+	ScrollDirection string `json:"scroll_direction,omitempty"`
+	// ScrollAmount is the number of scroll wheel clicks.
+	ScrollAmount int `json:"scroll_amount,omitempty"`
+	// Duration is the number of seconds to hold a key or wait.
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// ParseComputerAction decodes the arguments of a computer-use function call.
+func ParseComputerAction(call *genai.FunctionCall) (*ComputerAction, error) {
+	var action ComputerAction
+	if err := decodeToolCall(call, ComputerUseToolName, &action); err != nil {
+		return nil, err
+	}
+	if action.Action == "" {
+		return nil, fmt.Errorf("computer action is missing the action field")
+	}
+	return &action, nil
+}
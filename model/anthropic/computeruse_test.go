@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestComputerUse(t *testing.T) {
+	var gotBody, gotBeta string
+	m := newTestModel(t, &Config{ComputerUse: &ComputerUse{DisplayWidthPx: 1024, DisplayHeightPx: 768}}, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotBeta = r.Header.Get("anthropic-beta")
+		writeJSON(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"tool_use","id":"toolu_1","name":"computer","input":{"action":"left_click","coordinate":[100,200]}}],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Open the browser", "user")},
+		Config: &genai.GenerateContentConfig{
+			Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+				{Name: ComputerUseToolName, Description: "executor"},
+				{Name: "lookup"},
+			}}},
+		},
+	}
+	got := collect(t, m, req, false)
+
+	if gotBeta != computerUseBeta {
+		t.Errorf("anthropic-beta = %q, want %q", gotBeta, computerUseBeta)
+	}
+	wantTool := `{"display_height_px":768,"display_width_px":1024,"name":"computer","type":"computer_20250124"}`
+	if !strings.Contains(gotBody, wantTool) {
+		t.Errorf("request body = %s, want tool %s", gotBody, wantTool)
+	}
+	if strings.Contains(gotBody, `"executor"`) {
+		t.Errorf("request body = %s, want computer declaration replaced", gotBody)
+	}
+	if !strings.Contains(gotBody, `"name":"lookup"`) {
+		t.Errorf("request body = %s, want other tools kept", gotBody)
+	}
+
+	action, err := ParseComputerAction(got[0].Content.Parts[0].FunctionCall)
+	if err != nil {
+		t.Fatalf("ParseComputerAction() error = %v", err)
+	}
+	want := &ComputerAction{Action: ComputerActionLeftClick, Coordinate: []int{100, 200}}
+	if diff := cmp.Diff(want, action); diff != "" {
+		t.Errorf("ParseComputerAction() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewModel_InvalidComputerUse(t *testing.T) {
+	_, err := NewModel(t.Context(), "claude-sonnet-4-20250514", &Config{APIKey: "test-api-key", ComputerUse: &ComputerUse{DisplayWidthPx: 1024}})
+	if err == nil || !strings.Contains(err.Error(), "invalid ComputerUse") {
+		t.Fatalf("NewModel() error = %v, want invalid ComputerUse", err)
+	}
+}
+
+func TestParseComputerAction_WrongTool(t *testing.T) {
+	if _, err := ParseComputerAction(&genai.FunctionCall{Name: "lookup"}); err == nil {
+		t.Error("ParseComputerAction() error = nil, want error")
+	}
+}
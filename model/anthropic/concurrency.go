@@ -0,0 +1,46 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"iter"
+
+	"google.golang.org/adk/model"
+)
+
+// limited runs seq once fewer than Config.MaxConcurrentRequests calls of the
+// model are in flight, holding a slot until seq is done. If ctx is done
+// first, it yields the context's error instead.
+func (m *anthropicModel) limited(ctx context.Context, seq iter.Seq2[*model.LLMResponse, error]) iter.Seq2[*model.LLMResponse, error] {
+	if m.sem == nil {
+		return seq
+	}
+	return func(yield func(*model.LLMResponse, error) bool) {
+		select {
+		case m.sem <- struct{}{}:
+		case <-ctx.Done():
+			yield(nil, ctx.Err())
+			return
+		}
+		defer func() { <-m.sem }()
+
+		for resp, err := range seq {
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
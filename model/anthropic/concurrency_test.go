@@ -0,0 +1,108 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestGenerate_MaxConcurrentRequests(t *testing.T) {
+	const limit, calls = 2, 6
+
+	var inFlight, peak atomic.Int32
+	arrived := make(chan struct{}, calls)
+	release := make(chan struct{})
+	m := newTestModel(t, &Config{MaxConcurrentRequests: limit}, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		arrived <- struct{}{}
+		<-release
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	var wg sync.WaitGroup
+	for range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, err := range m.GenerateContent(t.Context(), req, false) {
+				if err != nil {
+					t.Errorf("GenerateContent() error = %v", err)
+				}
+			}
+		}()
+	}
+
+	for range limit {
+		<-arrived
+	}
+	select {
+	case <-arrived:
+		t.Errorf("more than %d requests in flight", limit)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	wg.Wait()
+
+	if got := peak.Load(); got != limit {
+		t.Errorf("peak requests in flight = %d, want %d", got, limit)
+	}
+}
+
+func TestGenerate_MaxConcurrentRequestsCancel(t *testing.T) {
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	m := newTestModel(t, &Config{MaxConcurrentRequests: 1}, func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-release
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, err := range m.GenerateContent(t.Context(), req, false) {
+			if err != nil {
+				t.Errorf("GenerateContent() error = %v", err)
+			}
+		}
+	}()
+	<-arrived
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	for _, err := range m.GenerateContent(ctx, req, false) {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("GenerateContent() error = %v, want context.DeadlineExceeded", err)
+		}
+	}
+
+	close(release)
+	<-done
+}
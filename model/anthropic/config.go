@@ -14,31 +14,338 @@
 
 package anthropic
 
+import (
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"go.opentelemetry.io/otel/trace"
+
+	"google.golang.org/adk/model"
+)
+
+// Service tier constants for [Config.ServiceTier].
+const (
+	// ServiceTierAuto uses priority capacity when available, falling back to standard.
+	ServiceTierAuto = "auto"
+
+	// ServiceTierStandardOnly always uses standard capacity.
+	ServiceTierStandardOnly = "standard_only"
+)
+
+// Cache TTL constants for [Config.CacheTTL].
+const (
+	// CacheTTL5Minutes keeps cached prompt prefixes for 5 minutes, refreshed
+	// each time they are read. This is the API default.
+	CacheTTL5Minutes = "5m"
+
+	// CacheTTL1Hour keeps cached prompt prefixes for 1 hour. Writing to this
+	// cache costs more than to the 5 minute cache (2x instead of 1.25x the
+	// base input token price), reads cost the same.
+	CacheTTL1Hour = "1h"
+)
+
 // Config holds configuration for creating an Anthropic Claude model.
 type Config struct {
 	// APIKey is the Anthropic API key for direct API access.
-	// If not provided, it will be read from the ANTHROPIC_API_KEY environment variable.
+	// If not provided, it will be read from APIKeyFile, or else from the
+	// ANTHROPIC_API_KEY environment variable.
 	// This is only used when Variant is VariantAnthropicAPI.
 	APIKey string
 
+	// APIKeyFile is the path of a file holding the API key, such as a
+	// mounted secret. Surrounding whitespace is trimmed. If not provided, it
+	// is read from the ANTHROPIC_API_KEY_FILE environment variable. The file
+	// is read when the model is created.
+	APIKeyFile string
+
 	// VertexProjectID is the Google Cloud project ID for Vertex AI access.
-	// If not provided, it will be read from the GOOGLE_CLOUD_PROJECT environment variable.
+	// If not provided, it will be read from the GOOGLE_CLOUD_PROJECT environment variable,
+	// or else from Application Default Credentials: the project of a service
+	// account key, or of the metadata server on Google Cloud.
 	// This is only used when Variant is VariantVertexAI.
 	VertexProjectID string
 
 	// VertexRegion is the Google Cloud region for Vertex AI access.
 	// If not provided, it will be read from the GOOGLE_CLOUD_REGION environment variable.
+	// On Google Cloud, the region of the metadata server's zone is used if
+	// neither is set; set it explicitly if Claude is not offered there.
 	// Common regions include "us-central1", "us-east5", and "europe-west1".
 	// This is only used when Variant is VariantVertexAI.
 	VertexRegion string
 
+	// VertexFallbackRegions are the Vertex AI regions to fail over to, in
+	// order, when a call fails because VertexRegion is overloaded (HTTP 529)
+	// or unavailable (HTTP 503), after any retries of RetryPolicy. Streaming
+	// calls only fail over before any event has been received.
+	// This is only used when Variant is VariantVertexAI.
+	VertexFallbackRegions []string
+
 	// Variant determines which backend to use for API calls.
 	// Valid values are VariantAnthropicAPI and VariantVertexAI.
 	// If empty, the variant is determined from the ANTHROPIC_USE_VERTEX environment variable.
 	Variant string
 
+	// DisableClientSharing gives the model its own client. By default, models
+	// created with the same backend, credentials and client settings share a
+	// client, and with it a connection pool.
+	DisableClientSharing bool
+
 	// DefaultMaxTokens is the default maximum number of tokens to generate.
 	// Anthropic requires max_tokens to be explicitly set for all requests.
-	// If not provided, defaults to 4096.
+	// If not provided, defaults to 4096. A value set on the request context
+	// with WithMaxTokens, then the request's MaxOutputTokens, take precedence.
 	DefaultMaxTokens int
+
+	// DefaultTemperature, DefaultTopP and DefaultTopK are sent when a request
+	// does not set Temperature, TopP or TopK in its GenerateContentConfig.
+	// If nil, the API defaults are used.
+	DefaultTemperature *float64
+	DefaultTopP        *float64
+	DefaultTopK        *int
+
+	// ModelTemperatures maps model name prefixes, such as "claude-3-5-haiku",
+	// to the temperature sent when neither the request nor DefaultTemperature
+	// sets one. It extends and overrides the built-in table of per-model
+	// defaults; the longest prefix matching the model name applies. Models
+	// without an entry use the API default.
+	ModelTemperatures map[string]float64
+
+	// DefaultStopSequences are sent with every request, in addition to any
+	// StopSequences set in the request's GenerateContentConfig. Duplicates
+	// are sent once.
+	DefaultStopSequences []string
+
+	// SystemPrefix and SystemSuffix, if set, are sent as additional system
+	// text blocks before and after the request's system instruction, for
+	// instructions that every agent using the model must follow, such as
+	// organization-wide guardrails. They are sent even when the request has
+	// no system instruction.
+	SystemPrefix string
+	SystemSuffix string
+
+	// EmptyConversationPrompt is sent as the user message when a request has
+	// no contents, since the API requires at least one message. Set it to
+	// match the language of the system instruction. If empty, an English
+	// prompt referring to the system instruction is used.
+	EmptyConversationPrompt string
+
+	// DisableAutoUserContent sends the request's contents as they are. By
+	// default, a user message is appended when the contents are empty or end
+	// with a model turn that has no function calls, since the API expects the
+	// conversation to end with a user message. Disable it for conversations
+	// that deliberately end with an assistant prefill, or that manage the
+	// alternation of turns themselves. EmptyConversationPrompt is then unused.
+	DisableAutoUserContent bool
+
+	// MaxImageDimension, if set, downscales inline JPEG, PNG and single-frame
+	// GIF images whose width or height exceeds it, preserving the aspect ratio
+	// and format, to stay within Anthropic's size limits and save tokens.
+	// Images that cannot be decoded are sent unchanged.
+	MaxImageDimension int
+
+	// MaxImagesPerRequest, MaxImageBytes and MaxTotalImageBytes limit the
+	// number of inline images in a request, the size of each image and their
+	// combined size (in bytes before base64 encoding). Requests that exceed a
+	// limit fail before being sent, with an error naming the offending image.
+	// Zero uses Anthropic's documented limits: 100 images of up to 5 MB each,
+	// and 24 MB in total so that the encoded request stays within 32 MB.
+	// A negative value disables the check.
+	MaxImagesPerRequest int
+	MaxImageBytes       int
+	MaxTotalImageBytes  int
+
+	// StreamBufferChars and StreamBufferDuration coalesce streamed text and
+	// thinking deltas into larger partial responses. Buffered deltas are
+	// yielded once they reach StreamBufferChars bytes, once the oldest is
+	// StreamBufferDuration old (checked as deltas arrive), and at the end of
+	// each content block. If both are zero, every delta is yielded as it
+	// arrives.
+	StreamBufferChars    int
+	StreamBufferDuration time.Duration
+
+	// StreamIdleTimeout, if set, aborts streams that receive no data for
+	// longer than this, with an error wrapping ErrStreamIdleTimeout. Any data
+	// restarts the timer, including the ping events the API sends to keep
+	// long streams alive, so it can be shorter than the total duration of a
+	// response. The timer starts once the response arrives, so retries and
+	// their backoff do not count, and it is paused while the caller handles
+	// each response, so slow callers do not trip it. Non-streaming calls are
+	// not affected.
+	StreamIdleTimeout time.Duration
+
+	// MergeTextParts concatenates adjacent text parts of final responses,
+	// such as the blocks Claude splits cited text into, into a single part.
+	// Function calls and thinking parts are kept as they are, and text on
+	// either side of them is not merged across them. Partial responses are
+	// not affected.
+	MergeTextParts bool
+
+	// ServiceTier selects the Anthropic service tier for requests.
+	// Valid values are ServiceTierAuto and ServiceTierStandardOnly.
+	// If empty, the API default is used. The tier that actually served a
+	// response is reported under MetadataKeyServiceTier.
+	ServiceTier string
+
+	// DefaultUserID is sent as metadata.user_id on every request, letting
+	// Anthropic attribute usage to an end user for abuse monitoring.
+	// It should be a stable, opaque identifier (for example a hash); never
+	// include personally identifying information. A value set on the request
+	// context with WithUserID takes precedence.
+	DefaultUserID string
+
+	// RetryPolicy enables retries with exponential backoff and jitter. When
+	// set, it replaces the SDK's built-in retries and retries the same
+	// failures (see RetryPolicy), so that the policy fully controls the number
+	// of attempts. If nil, the SDK's default retry behavior applies.
+	RetryPolicy *RetryPolicy
+
+	// MaxConcurrentRequests limits the number of calls of the model that are
+	// in flight at once, across all goroutines sharing it. Further calls wait
+	// for one to finish, or for their context to be done. A streaming call
+	// holds its slot until the stream ends. If zero or negative, calls are not
+	// limited.
+	MaxConcurrentRequests int
+
+	// EnforceContextLimit counts the input tokens of each request with the
+	// token counting endpoint before sending it, and fails with an error
+	// wrapping ErrContextLimitExceeded if they do not leave room for the
+	// maximum number of output tokens in the model's context window. This
+	// costs an extra round trip per call, but turns a vague API error into an
+	// actionable one.
+	EnforceContextLimit bool
+
+	// ContextWindow overrides the context window, in tokens, that
+	// EnforceContextLimit checks requests against. If zero, Claude's 200K
+	// token window is used, or 1M tokens for Claude Sonnet 4 models when the
+	// long context beta is enabled through BetaHeaders.
+	ContextWindow int
+
+	// AutoTruncate drops the oldest contents of requests that do not fit in
+	// the context window, instead of failing like EnforceContextLimit, which
+	// it implies. Contents are dropped with TruncationStrategy until the
+	// request fits. The tokens of the dropped contents are estimated from
+	// their size, so the tokens are counted a few times at most rather than
+	// after each step. If the strategy cannot drop enough, the call fails with
+	// an error wrapping ErrContextLimitExceeded. The request's contents are
+	// not modified.
+	AutoTruncate bool
+
+	// TruncationStrategy drops contents for AutoTruncate. If nil,
+	// DropOldestTurns is used.
+	TruncationStrategy TruncationStrategy
+
+	// ComputerUse enables Claude's computer-use tool with the given display.
+	// The required beta header is sent automatically.
+	ComputerUse *ComputerUse
+
+	// BashTool enables Claude's bash tool (bash_20250124). Register a function
+	// tool named BashToolName to run the commands, using ParseBashCommand to
+	// decode them. The required beta header is sent automatically.
+	BashTool bool
+
+	// TextEditorTool enables Claude's text editor tool (text_editor_20250124).
+	// Register a function tool named TextEditorToolName to apply the edits,
+	// using ParseTextEditorCommand to decode them. The required beta header is
+	// sent automatically.
+	TextEditorTool bool
+
+	// CodeExecutionTool enables Claude's code execution tool
+	// (code_execution_20250522), which runs code in a sandboxed container
+	// hosted by Anthropic. The calls and their results are executed by
+	// Anthropic and reported as ServerToolBlockMIMEType parts, and the
+	// container under MetadataKeyContainer; pass it to WithContainer to reuse
+	// its files in later requests. The required beta header is sent
+	// automatically.
+	CodeExecutionTool bool
+
+	// MCPServers lists remote MCP servers whose tools Claude may call through
+	// Anthropic's MCP connector. The required beta header is sent automatically.
+	MCPServers []MCPServerConfig
+
+	// CacheTools places a prompt cache breakpoint after the tool definitions,
+	// so that large tool schemas are read from the cache instead of being
+	// billed as new input tokens on every turn. It uses one of the 4
+	// breakpoints allowed per request (see NewCacheBreakpointPart). Cache
+	// reads are reported in UsageMetadata.CachedContentTokenCount.
+	CacheTools bool
+
+	// StrictToolSchemas closes the input schemas of function tools, and of
+	// the objects nested in them, with additionalProperties set to false and
+	// every property required, so that Claude neither invents arguments nor
+	// leaves any out. Declare optional arguments as nullable instead.
+	StrictToolSchemas bool
+
+	// CacheTTL sets how long the prompt prefixes cached by CacheTools and by
+	// the cache breakpoints of system instructions are kept: CacheTTL5Minutes
+	// or CacheTTL1Hour. The 1 hour cache suits agents whose turns are more
+	// than 5 minutes apart, but cache writes are priced higher. If empty, the
+	// API default of 5 minutes applies.
+	CacheTTL string
+
+	// InterleavedThinking enables extended thinking, including between tool
+	// calls, and sends the required beta header. The thinking budget is taken
+	// from the request's ThinkingConfig.ThinkingBudget, defaulting to 2048
+	// tokens; budgets under 1024 tokens are rejected. Because thinking does
+	// not support them, Temperature and TopK are not sent while it is enabled.
+	//
+	// Without this option, thinking (not interleaved) is enabled for requests
+	// whose ThinkingConfig sets IncludeThoughts or a ThinkingBudget, unless
+	// the budget is 0, which turns thinking off as in genai.
+	InterleavedThinking bool
+
+	// BetaHeaders lists additional anthropic-beta values to send with every
+	// request, for beta features this package has no dedicated option for.
+	// They are merged with the betas required by other options, and duplicates
+	// are sent once.
+	BetaHeaders []string
+
+	// APIVersion overrides the anthropic-version header sent with every
+	// request, for gateways that pin a specific API version. If empty, the
+	// version chosen by the Anthropic SDK is sent. Vertex AI ignores the header.
+	APIVersion string
+
+	// ExtraHeaders are HTTP headers sent with every request, such as tenant
+	// or tracing headers required by a proxy. Use [WithHeaders] to add
+	// headers to individual requests.
+	ExtraHeaders map[string]string
+
+	// RecordDir and ReplayDir enable golden-file testing against real API
+	// responses. Each HTTP response received from the API is saved in
+	// RecordDir, in a file named after a hash of the request's method, path
+	// and body (headers, including credentials, are not part of the hash).
+	// Requests whose response is found in ReplayDir are answered from it
+	// without calling the API; when no response is found, the request fails
+	// unless RecordDir is also set. Setting both to the same directory records
+	// on the first run and replays thereafter. Replayed requests still need a
+	// configured client, so Vertex AI requires credentials to be available.
+	RecordDir string
+	ReplayDir string
+
+	// OnRequest, if set, is called with the request parameters before each
+	// call to the Messages API, for example to log the JSON sent on the wire.
+	// It is called once per call, regardless of retries.
+	OnRequest func(params anthropic.MessageNewParams)
+
+	// OnResponse, if set, is called with the message returned by the Messages
+	// API. For streaming calls, it is called once with the accumulated message
+	// after the stream completes successfully.
+	OnResponse func(msg *anthropic.Message)
+
+	// ResponseTransform, if set, is called with the final response of each
+	// call, after it is converted, and the response it returns is used
+	// instead, for post-processing such as stripping a preamble from the
+	// text. It may modify and return its argument; a nil result keeps it.
+	// Partial streaming responses are not transformed.
+	ResponseTransform func(resp *model.LLMResponse) *model.LLMResponse
+
+	// Tracer, if set, traces each model call with a client span carrying the
+	// OpenTelemetry generative AI attributes (model, provider, token usage,
+	// finish reason and errors). Streaming calls also record the time to the
+	// first token. If nil, calls are traced only when the context carries a
+	// span, using a tracer from that span's provider.
+	Tracer trace.Tracer
+
+	// MetricsRecorder, if set, receives the token usage, latency and error of
+	// every model call. If nil, no metrics are recorded.
+	MetricsRecorder MetricsRecorder
 }
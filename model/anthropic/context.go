@@ -0,0 +1,94 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"maps"
+)
+
+type ctxKey int
+
+const (
+	userIDCtxKey ctxKey = iota
+	maxTokensCtxKey
+	headersCtxKey
+	containerCtxKey
+)
+
+// WithUserID returns a context that sets the Anthropic metadata.user_id for
+// requests made with it, overriding [Config.DefaultUserID].
+//
+// The user ID should be a stable, opaque identifier such as a UUID or hash.
+// Do not include names, email addresses, or other identifying information.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDCtxKey, userID)
+}
+
+// userIDFromContext returns the user ID set by WithUserID, if any.
+func userIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDCtxKey).(string)
+	return userID
+}
+
+// WithMaxTokens returns a context that sets the maximum number of tokens to
+// generate for requests made with it, so that agents sharing a model can use
+// different output lengths. It takes precedence over the request's
+// MaxOutputTokens, which takes precedence over [Config.DefaultMaxTokens].
+// Values less than 1 are ignored.
+func WithMaxTokens(ctx context.Context, maxTokens int) context.Context {
+	return context.WithValue(ctx, maxTokensCtxKey, maxTokens)
+}
+
+// maxTokensFromContext returns the maximum set by WithMaxTokens, or 0.
+func maxTokensFromContext(ctx context.Context) int {
+	maxTokens, _ := ctx.Value(maxTokensCtxKey).(int)
+	return max(maxTokens, 0)
+}
+
+// WithHeaders returns a context that adds headers to the HTTP requests made
+// with it, such as a request ID for tracing. They are sent in addition to
+// [Config.ExtraHeaders] and headers already set on ctx, and take precedence
+// over both.
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := maps.Clone(headersFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(headers))
+	}
+	maps.Copy(merged, headers)
+	return context.WithValue(ctx, headersCtxKey, merged)
+}
+
+// headersFromContext returns the headers set by WithHeaders, if any.
+func headersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersCtxKey).(map[string]string)
+	return headers
+}
+
+// WithContainer returns a context that reuses the code execution container
+// with the given ID for requests made with it, so that files written by code
+// executed in earlier turns are still available. The ID of the container used
+// by a request is reported under [MetadataKeyContainer] in the response's
+// CustomMetadata; persist it with the conversation and pass it on with the
+// next request.
+func WithContainer(ctx context.Context, containerID string) context.Context {
+	return context.WithValue(ctx, containerCtxKey, containerID)
+}
+
+// containerFromContext returns the container ID set by WithContainer, if any.
+func containerFromContext(ctx context.Context) string {
+	containerID, _ := ctx.Value(containerCtxKey).(string)
+	return containerID
+}
@@ -0,0 +1,198 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+)
+
+// ErrContextLimitExceeded reports that a request does not fit in the context
+// window of the model. It is returned before calling the model if
+// [Config.EnforceContextLimit] is set.
+var ErrContextLimitExceeded = errors.New("anthropic: context window exceeded")
+
+const (
+	// defaultContextWindow is the context window of Claude models, in tokens.
+	defaultContextWindow = 200_000
+	// longContextWindow is the context window of the models that support the
+	// long context beta, when it is enabled.
+	longContextWindow = 1_000_000
+	// longContextBeta is the prefix of the long context beta header.
+	longContextBeta = "context-1m"
+)
+
+// longContextModels lists the prefixes of the models that support the long
+// context beta.
+var longContextModels = []string{"claude-sonnet-4"}
+
+// contextWindow returns the number of tokens the model accepts in a request,
+// input and output combined.
+func (m *anthropicModel) contextWindow() int {
+	if m.cfg.ContextWindow > 0 {
+		return m.cfg.ContextWindow
+	}
+	longContext := slices.ContainsFunc(betaHeaders(&m.cfg), func(beta string) bool {
+		return strings.HasPrefix(beta, longContextBeta)
+	})
+	if longContext && slices.ContainsFunc(longContextModels, func(prefix string) bool {
+		return strings.HasPrefix(string(m.name), prefix)
+	}) {
+		return longContextWindow
+	}
+	return defaultContextWindow
+}
+
+// countTokensFields lists the parameters of a Messages API request that the
+// token counting endpoint accepts.
+var countTokensFields = []string{"model", "messages", "system", "tools", "tool_choice", "thinking", "mcp_servers"}
+
+// countTokens returns the number of input tokens of the request made with params.
+func (m *anthropicModel) countTokens(ctx context.Context, params *anthropic.MessageNewParams) (int64, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return 0, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return 0, err
+	}
+	for key := range fields {
+		if !slices.Contains(countTokensFields, key) {
+			delete(fields, key)
+		}
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return 0, err
+	}
+	var countParams anthropic.MessageCountTokensParams
+	if err := json.Unmarshal(data, &countParams); err != nil {
+		return 0, err
+	}
+
+	count, err := m.client.Messages.CountTokens(ctx, countParams, requestOptions(ctx)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", classifyError(err))
+	}
+	return count.InputTokens, nil
+}
+
+// checkContextLimit returns an error wrapping ErrContextLimitExceeded if the
+// input tokens of params plus its MaxTokens exceed the context window.
+func (m *anthropicModel) checkContextLimit(ctx context.Context, params *anthropic.MessageNewParams) error {
+	input, err := m.countTokens(ctx, params)
+	if err != nil {
+		return err
+	}
+	window := int64(m.contextWindow())
+	if input+params.MaxTokens > window {
+		return m.contextLimitError(input, params.MaxTokens, window)
+	}
+	return nil
+}
+
+// contextLimitError returns the error wrapping ErrContextLimitExceeded for a
+// request of input tokens allowing maxTokens output tokens.
+func (m *anthropicModel) contextLimitError(input, maxTokens, window int64) error {
+	return fmt.Errorf("%w: the request has %d input tokens and allows %d output tokens, %d tokens more than the %d token context window of %s; shorten the conversation or lower the maximum number of output tokens",
+		ErrContextLimitExceeded, input, maxTokens, input+maxTokens-window, window, m.name)
+}
+
+// maxTruncationCounts bounds the number of token counts made by truncateToFit.
+const maxTruncationCounts = 4
+
+// truncateToFit drops contents with the configured TruncationStrategy until
+// the request made with params fits in the context window, rebuilding the
+// messages of params from the remaining contents. It returns an error
+// wrapping ErrContextLimitExceeded if the strategy cannot drop enough.
+//
+// Rather than counting tokens again after each call to the strategy, the
+// tokens of the dropped messages are estimated from their share of the size
+// of the counted request, and contents are dropped until the estimate covers
+// the excess. The result is then counted, at most maxTruncationCounts times
+// in all.
+func (m *anthropicModel) truncateToFit(ctx context.Context, contents []*genai.Content, params *anthropic.MessageNewParams, prefill bool) error {
+	truncate := m.cfg.TruncationStrategy
+	if truncate == nil {
+		truncate = DropOldestTurns
+	}
+	window := int64(m.contextWindow())
+	// The last count checks the final result
+	for range maxTruncationCounts - 1 {
+		input, err := m.countTokens(ctx, params)
+		if err != nil {
+			return err
+		}
+		excess := input + params.MaxTokens - window
+		if excess <= 0 {
+			return nil
+		}
+		size, err := requestSize(params)
+		if err != nil {
+			return err
+		}
+		messagesSize, err := jsonSize(params.Messages)
+		if err != nil {
+			return err
+		}
+
+		var messages []anthropic.MessageParam
+		for dropped := int64(0); dropped < excess; {
+			truncated := truncate(contents)
+			if len(truncated) >= len(contents) {
+				if messages == nil {
+					return m.contextLimitError(input, params.MaxTokens, window)
+				}
+				break
+			}
+			contents = truncated
+
+			if messages, err = converters.ContentsToMessages(contents); err != nil {
+				return fmt.Errorf("failed to convert contents: %w", err)
+			}
+			if prefill {
+				messages = append(messages, converters.JSONPrefillMessage())
+			}
+			remaining, err := jsonSize(messages)
+			if err != nil {
+				return err
+			}
+			dropped = input * (messagesSize - remaining) / size
+		}
+		params.Messages = messages
+	}
+	return m.checkContextLimit(ctx, params)
+}
+
+// requestSize returns the size of the JSON encoding of params, at least 1.
+func requestSize(params *anthropic.MessageNewParams) (int64, error) {
+	size, err := jsonSize(params)
+	return max(size, 1), err
+}
+
+// jsonSize returns the size of the JSON encoding of v.
+func jsonSize(v any) (int64, error) {
+	data, err := json.Marshal(v)
+	return int64(len(data)), err
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestGenerate_EnforceContextLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		inputTokens int
+		wantErr     bool
+	}{
+		{name: "fits", inputTokens: 150_000},
+		{name: "exceeds", inputTokens: 199_000, wantErr: true},
+		{name: "context_window", cfg: Config{ContextWindow: 8_000}, inputTokens: 5_000, wantErr: true},
+		{name: "long_context_beta", cfg: Config{BetaHeaders: []string{"context-1m-2025-08-07"}}, inputTokens: 500_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var countFields []string
+			messagesCalled := false
+			cfg := tt.cfg
+			cfg.EnforceContextLimit = true
+			cfg.DefaultMaxTokens = 4096
+			m := newTestModel(t, &cfg, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v1/messages/count_tokens" {
+					body, _ := io.ReadAll(r.Body)
+					var fields map[string]any
+					if err := json.Unmarshal(body, &fields); err != nil {
+						t.Errorf("count_tokens body: %v", err)
+					}
+					for key := range fields {
+						countFields = append(countFields, key)
+					}
+					writeJSON(w, fmt.Sprintf(`{"input_tokens":%d}`, tt.inputTokens))
+					return
+				}
+				messagesCalled = true
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+				Config:   &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("Be brief.", "system")},
+			}
+			var err error
+			for _, e := range m.GenerateContent(t.Context(), req, false) {
+				err = e
+			}
+
+			if got := errors.Is(err, ErrContextLimitExceeded); got != tt.wantErr {
+				t.Fatalf("GenerateContent() error = %v, want ErrContextLimitExceeded: %v", err, tt.wantErr)
+			}
+			if messagesCalled == tt.wantErr {
+				t.Errorf("model called = %v, want %v", messagesCalled, !tt.wantErr)
+			}
+			slices.Sort(countFields)
+			if diff := cmp.Diff([]string{"messages", "model", "system"}, countFields); diff != "" {
+				t.Errorf("count_tokens fields mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -47,6 +47,11 @@
 // Alternatively, set the ANTHROPIC_USE_VERTEX environment variable to "1" or "true"
 // to automatically use Vertex AI without specifying the variant in code.
 //
+// Vertex AI requests authenticate with Application Default Credentials. If the
+// credentials cannot produce an access token (for example because they expired
+// mid-session), the response has ErrorCode set to [ErrorCodeAuthentication] and
+// an ErrorMessage explaining how to refresh them.
+//
 // # Supported Models
 //
 // The package supports all Anthropic Claude models, including:
@@ -64,13 +69,88 @@
 //
 // For Vertex AI, model names follow the format: claude-{variant}-{version}@{date}
 //
+// Use [ListModels] to discover the models available to an API key at runtime.
+//
 // # Features
 //
 // The package supports:
 //   - Streaming and non-streaming responses
-//   - Tool/function calling
-//   - Extended thinking (mapped to genai.Part with Thought=true)
+//   - Tool/function calling, including tool results made of text, images and
+//     documents (see [FunctionResponsePartsKey]). A ToolConfig with function
+//     calling mode NONE keeps the tools defined but stops Claude from calling
+//     them, so prompt cache entries covering the tools stay valid.
+//   - Extended thinking (mapped to genai.Part with Thought=true), enabled by
+//     the request's ThinkingConfig, including interleaved thinking between
+//     tool calls (beta, see [Config.InterleavedThinking]). max_tokens is raised
+//     by the thinking budget when it does not exceed it.
+//   - An estimate of the output tokens spent thinking, in the usage metadata's
+//     ThoughtsTokenCount. The API does not report it, so it is derived from
+//     the length of the thinking text. As in genai, CandidatesTokenCount
+//     excludes it, and TotalTokenCount includes it.
 //   - Multimodal inputs (text, images)
 //   - PDF document processing (beta)
-//   - System instructions
+//   - Plain text documents (inline), with citations enabled
+//   - Pre-chunked custom content documents for RAG (see [NewDocumentChunksPart])
+//   - Search results for RAG, cited natively (see [NewSearchResultPart])
+//   - System instructions, with prompt cache breakpoints (see [NewCacheBreakpointPart]).
+//     Contents with the "system" role are appended to the system instruction,
+//     in order, for system prompts composed from several contents.
+//   - JSON output (see below)
+//   - Computer use (beta, see [ComputerUse])
+//   - Built-in bash and text editor tools (see [Config.BashTool] and [Config.TextEditorTool])
+//   - Remote MCP servers through the MCP connector (beta, see [MCPServerConfig])
+//   - Code execution (beta, see [Config.CodeExecutionTool]), with reuse of its
+//     containers across turns (see [WithContainer])
+//   - Asynchronous, discounted processing through the Message Batches API (see [Batcher])
+//
+// # Errors
+//
+// Failures reported by the API wrap [ErrRateLimited], [ErrOverloaded],
+// [ErrInvalidRequest] or [ErrAuthentication], so they can be told apart from
+// network errors with errors.Is. A request rejected because of an image or
+// document the API could not process gets a response with ErrorCode set to
+// [ErrorCodeInvalidMedia] instead, so that users can be asked to upload the
+// file again.
+//
+// # Streaming
+//
+// Partial responses carry text and thinking deltas as they arrive, and the
+// citations of the text being streamed in partials of their own. The
+// signature that closes a thinking block is sent as a thinking partial of its
+// own (with any thinking text still buffered), so it is not lost, but partials
+// are meant for display. The final response, which has TurnComplete set, holds
+// the complete content blocks with their signatures and is the one to persist
+// in conversation history. [CollectStream] consumes a stream and returns that
+// single consolidated response.
+//
+// # JSON Output
+//
+// Claude has no native JSON output mode. When the request's ResponseMIMEType is
+// "application/json", the assistant turn is prefilled with "{" so that the model
+// answers with a JSON object, and the prefill is restored in the returned text
+// (including the first streamed delta). Final responses that end normally are
+// validated, and a response that is not valid JSON is reported as an error.
+// The API does not accept the prefill with extended thinking, or after a
+// history that ends with an assistant turn, so it is skipped in those cases
+// and the answer relies on the prompt alone.
+//
+// Unlike Gemini, ResponseSchema is not enforced, so describe the expected shape
+// in the prompt. Top-level JSON arrays are not supported.
+//
+// # Testing
+//
+// [NewFakeModel] returns a model that answers with canned responses and records
+// the requests it receives, so agents can be unit tested without an API key.
+// To test the real conversion paths without a live key in CI, record API
+// responses once with [Config.RecordDir] and replay them with [Config.ReplayDir].
+//
+// # Unsupported Parameters
+//
+// Anthropic models return a single candidate per request, so a CandidateCount
+// greater than 1 is rejected with an error rather than silently ignored; call
+// GenerateContent once per candidate instead. Likewise, Seed, PresencePenalty,
+// FrequencyPenalty and ResponseModalities other than TEXT have no Anthropic
+// equivalent, and requests that set them fail with a descriptive error. The
+// API does not expose token log probabilities either, so requests that set
+// ResponseLogprobs or Logprobs are rejected too.
 package anthropic
@@ -0,0 +1,161 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"google.golang.org/adk/model"
+)
+
+// Errors classifying failed calls to the Messages API. The errors returned by
+// GenerateContent wrap one of them when the API reports the failure, so
+// callers can decide what to do with errors.Is. The underlying
+// *anthropic.Error, if any, is still available through errors.As. Errors that
+// wrap none of them, such as network failures, never reached the API or got
+// no answer from it.
+var (
+	// ErrRateLimited reports that a rate limit was exceeded (HTTP 429).
+	// Retry after a delay.
+	ErrRateLimited = errors.New("anthropic: rate limited")
+	// ErrOverloaded reports that the API is temporarily overloaded (HTTP 529).
+	// Retry after a delay.
+	ErrOverloaded = errors.New("anthropic: overloaded")
+	// ErrInvalidRequest reports a request the API rejected as malformed or too
+	// large (HTTP 400, 404, 413). Retrying it unchanged will fail again.
+	ErrInvalidRequest = errors.New("anthropic: invalid request")
+	// ErrAuthentication reports missing, invalid or insufficient credentials
+	// (HTTP 401, 403).
+	ErrAuthentication = errors.New("anthropic: authentication failed")
+)
+
+// streamErrorPrefix starts the errors reported by the SDK for error events
+// received in the middle of a stream.
+const streamErrorPrefix = "received error while streaming: "
+
+// classifiedError is an error with the sentinel error classifying it.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// classifyError wraps err with the sentinel error matching the failure the API
+// reported, if any.
+func classifyError(err error) error {
+	var kind error
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		kind = errorKindForStatus(apiErr.StatusCode)
+	} else if data, ok := strings.CutPrefix(err.Error(), streamErrorPrefix); ok {
+		var event struct {
+			Error struct {
+				Type string `json:"type"`
+			} `json:"error"`
+		}
+		if json.Unmarshal([]byte(data), &event) == nil {
+			kind = errorKindForType(event.Error.Type)
+		}
+	}
+	if kind == nil {
+		return err
+	}
+	return &classifiedError{kind: kind, err: err}
+}
+
+// errorKindForStatus returns the sentinel error for an HTTP status code.
+func errorKindForStatus(status int) error {
+	switch status {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusOverloaded:
+		return ErrOverloaded
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge:
+		return ErrInvalidRequest
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuthentication
+	default:
+		return nil
+	}
+}
+
+// errorKindForType returns the sentinel error for an API error type, as
+// reported in error events.
+func errorKindForType(typ string) error {
+	switch typ {
+	case "rate_limit_error":
+		return ErrRateLimited
+	case "overloaded_error":
+		return ErrOverloaded
+	case "invalid_request_error", "not_found_error", "request_too_large":
+		return ErrInvalidRequest
+	case "authentication_error", "permission_error":
+		return ErrAuthentication
+	default:
+		return nil
+	}
+}
+
+// ErrorCodeInvalidMedia is the ErrorCode of the response to a request the API
+// rejected because of an image or document it could not process, such as a
+// corrupt or oversized image. The ErrorMessage holds the API's explanation,
+// which names the offending content block, so that users can be asked to
+// upload the file again.
+const ErrorCodeInvalidMedia = "INVALID_MEDIA"
+
+// mediaErrorPattern matches the API error messages about an image or document
+// that could not be processed: validation errors of the source of an image or
+// document block, and the messages about an unreadable image or PDF. Errors
+// about the request as a whole, such as too many images, or about other
+// fields of a block, such as citations, do not match.
+var mediaErrorPattern = regexp.MustCompile(`\.(image|document)\.source\b|(?i)\bcould not process image\b|\bthe pdf specified\b`)
+
+// errorResponse returns the response reported for err instead of an error, if
+// any: authentication failures on Vertex AI and rejected media.
+func errorResponse(err error) *model.LLMResponse {
+	if resp := authErrorResponse(err); resp != nil {
+		return resp
+	}
+	return invalidMediaResponse(err)
+}
+
+// invalidMediaResponse returns a response with ErrorCodeInvalidMedia if err is
+// an invalid request error about an image or document, or nil otherwise.
+func invalidMediaResponse(err error) *model.LLMResponse {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return nil
+	}
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(apiErr.RawJSON()), &body) != nil || !mediaErrorPattern.MatchString(body.Error.Message) {
+		return nil
+	}
+	return &model.LLMResponse{
+		ErrorCode:    ErrorCodeInvalidMedia,
+		ErrorMessage: body.Error.Message,
+	}
+}
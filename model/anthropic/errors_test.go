@@ -0,0 +1,164 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestGenerate_ErrorClassification(t *testing.T) {
+	sentinels := []error{ErrRateLimited, ErrOverloaded, ErrInvalidRequest, ErrAuthentication}
+	tests := []struct {
+		status  int
+		errType string
+		want    error
+	}{
+		{status: http.StatusTooManyRequests, errType: "rate_limit_error", want: ErrRateLimited},
+		{status: statusOverloaded, errType: "overloaded_error", want: ErrOverloaded},
+		{status: http.StatusBadRequest, errType: "invalid_request_error", want: ErrInvalidRequest},
+		{status: http.StatusRequestEntityTooLarge, errType: "request_too_large", want: ErrInvalidRequest},
+		{status: http.StatusUnauthorized, errType: "authentication_error", want: ErrAuthentication},
+		{status: http.StatusForbidden, errType: "permission_error", want: ErrAuthentication},
+		{status: http.StatusInternalServerError, errType: "api_error"},
+	}
+
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%v", tt.errType, stream), func(t *testing.T) {
+				m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("X-Should-Retry", "false")
+					w.WriteHeader(tt.status)
+					fmt.Fprintf(w, `{"type":"error","error":{"type":%q,"message":"something went wrong"}}`, tt.errType)
+				})
+
+				err := generateError(t, m, stream)
+				for _, sentinel := range sentinels {
+					if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+						t.Errorf("errors.Is(%v, %v) = %v, want %v", err, sentinel, got, !got)
+					}
+				}
+				var apiErr *anthropic.Error
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+					t.Errorf("errors.As(%v, *anthropic.Error) = %v, want status %d", err, apiErr, tt.status)
+				}
+			})
+		}
+	}
+}
+
+func TestGenerateStream_ErrorEventClassification(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		events := textStreamEvents("Hello", "end_turn")[:3]
+		writeSSE(w, events...)
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+
+	if err := generateError(t, m, true); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("GenerateContent() error = %v, want ErrOverloaded", err)
+	}
+}
+
+func TestGenerate_TransportErrorUnclassified(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler) // drop the connection
+	})
+	m.cfg.RetryPolicy = &RetryPolicy{MaxAttempts: 1}
+
+	err := generateError(t, m, false)
+	if err == nil {
+		t.Fatal("GenerateContent() error = nil, want a transport error")
+	}
+	for _, sentinel := range []error{ErrRateLimited, ErrOverloaded, ErrInvalidRequest, ErrAuthentication} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("errors.Is(%v, %v) = true, want transport errors unclassified", err, sentinel)
+		}
+	}
+}
+
+// generateError returns the first error from calling m.
+func generateError(t *testing.T, m model.LLM, stream bool) error {
+	t.Helper()
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	for _, err := range m.GenerateContent(t.Context(), req, stream) {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGenerate_InvalidMedia(t *testing.T) {
+	const message = "messages.0.content.0.image.source.base64.data: The image was specified using the image/png media type, but does not appear to be a valid png image"
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"type":"error","error":{"type":"invalid_request_error","message":%q}}`, message)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{{Role: "user", Parts: []*genai.Part{
+				{InlineData: &genai.Blob{MIMEType: "image/png", Data: []byte("not a png")}},
+			}}}}
+			got := collect(t, m, req, stream)
+			if len(got) != 1 || got[0].ErrorCode != ErrorCodeInvalidMedia || got[0].ErrorMessage != message {
+				t.Errorf("responses = %+v, want a single response with ErrorCode %q and the API message", got, ErrorCodeInvalidMedia)
+			}
+		})
+	}
+}
+
+func TestMediaErrorPattern(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"messages.0.content.0.image.source.base64.data: The image was specified using the image/png media type, but does not appear to be a valid png image", true},
+		{"messages.0.content.1.image.source.base64: image exceeds 5 MB maximum: 5316640 bytes > 5242880 bytes", true},
+		{"messages.0.content.0.document.source.base64.data: The PDF specified was not valid.", true},
+		{"The PDF specified is password protected.", true},
+		{"Could not process image", true},
+		{"messages: too many images and documents: 101 > 100", false},
+		{"messages.0.content.1.document.citations.enabled: Citations must be enabled for all documents or none", false},
+		{"max_tokens: Field required", false},
+	}
+
+	for _, tt := range tests {
+		if got := mediaErrorPattern.MatchString(tt.message); got != tt.want {
+			t.Errorf("mediaErrorPattern.MatchString(%q) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate_InvalidRequestNotMedia(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: Field required"}}`)
+	})
+
+	if err := generateError(t, m, false); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("GenerateContent() error = %v, want ErrInvalidRequest", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// regionClient is a client for one Vertex AI region.
+type regionClient struct {
+	region string
+	client *anthropic.Client
+}
+
+// newFallbackClients returns a client for each of the Config.VertexFallbackRegions,
+// set up like the client of the primary region.
+func newFallbackClients(ctx context.Context, cfg *Config) ([]regionClient, error) {
+	var fallbacks []regionClient
+	for _, region := range cfg.VertexFallbackRegions {
+		regionCfg := *cfg
+		regionCfg.VertexRegion = region
+		client, _, err := newClient(ctx, &regionCfg)
+		if err != nil {
+			return nil, err
+		}
+		fallbacks = append(fallbacks, regionClient{region: region, client: client})
+	}
+	return fallbacks, nil
+}
+
+// withFailover calls call with the model's client and, while the call fails
+// because the region is overloaded or unavailable, with the client of each
+// fallback region in turn. Calls in each region are retried according to the
+// configured RetryPolicy before failing over.
+func (m *anthropicModel) withFailover(ctx context.Context, call func(client *anthropic.Client) error) error {
+	err := m.withRetry(ctx, func() error { return call(m.client) })
+	for _, fallback := range m.fallbacks {
+		if err == nil || !isRegionUnavailable(err) {
+			break
+		}
+		err = m.withRetry(ctx, func() error { return call(fallback.client) })
+	}
+	return err
+}
+
+// isRegionUnavailable reports whether err is an overloaded (HTTP 529) or
+// unavailable (HTTP 503) API error, which another region may not return.
+func isRegionUnavailable(err error) bool {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == statusOverloaded || apiErr.StatusCode == http.StatusServiceUnavailable
+}
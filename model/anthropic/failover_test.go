@@ -0,0 +1,172 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// newFailoverModel returns a model whose client calls the primary region of
+// the server and whose fallback clients call the given regions, each under
+// the path of its name.
+func newFailoverModel(t *testing.T, srv *httptest.Server, primary string, fallbacks ...string) *anthropicModel {
+	t.Helper()
+	regionClientFor := func(region string) *anthropic.Client {
+		client := anthropic.NewClient(option.WithBaseURL(srv.URL+"/"+region+"/"), option.WithAPIKey("test-api-key"), option.WithMaxRetries(0))
+		return &client
+	}
+	m := &anthropicModel{
+		client:           regionClientFor(primary),
+		name:             "claude-sonnet-4@20250514",
+		variant:          VariantVertexAI,
+		defaultMaxTokens: defaultMaxTokens,
+	}
+	for _, region := range fallbacks {
+		m.fallbacks = append(m.fallbacks, regionClient{region: region, client: regionClientFor(region)})
+	}
+	return m
+}
+
+func TestGenerate_VertexRegionFailover(t *testing.T) {
+	tests := []struct {
+		name        string
+		failing     map[string]int // region -> status
+		wantRegions []string
+		wantErr     error
+	}{
+		{
+			name:        "primary_overloaded",
+			failing:     map[string]int{"us-east5": statusOverloaded},
+			wantRegions: []string{"us-east5", "europe-west1"},
+		},
+		{
+			name:        "primary_unavailable",
+			failing:     map[string]int{"us-east5": http.StatusServiceUnavailable},
+			wantRegions: []string{"us-east5", "europe-west1"},
+		},
+		{
+			name:        "first_fallback_overloaded",
+			failing:     map[string]int{"us-east5": statusOverloaded, "europe-west1": statusOverloaded},
+			wantRegions: []string{"us-east5", "europe-west1", "asia-southeast1"},
+		},
+		{
+			name:        "all_overloaded",
+			failing:     map[string]int{"us-east5": statusOverloaded, "europe-west1": statusOverloaded, "asia-southeast1": statusOverloaded},
+			wantRegions: []string{"us-east5", "europe-west1", "asia-southeast1"},
+			wantErr:     ErrOverloaded,
+		},
+		{
+			name:        "invalid_request",
+			failing:     map[string]int{"us-east5": http.StatusBadRequest},
+			wantRegions: []string{"us-east5"},
+			wantErr:     ErrInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%v", tt.name, stream), func(t *testing.T) {
+				var mu sync.Mutex
+				var regions []string
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					region, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+					mu.Lock()
+					regions = append(regions, region)
+					mu.Unlock()
+					if status, ok := tt.failing[region]; ok {
+						w.Header().Set("Content-Type", "application/json")
+						w.Header().Set("X-Should-Retry", "false")
+						w.WriteHeader(status)
+						fmt.Fprintf(w, `{"type":"error","error":{"type":"overloaded_error","message":"%s is busy"}}`, region)
+						return
+					}
+					if stream {
+						writeSSE(w, textStreamEvents("ok", "end_turn")...)
+						return
+					}
+					writeJSON(w, okMessage)
+				}))
+				t.Cleanup(srv.Close)
+				m := newFailoverModel(t, srv, "us-east5", "europe-west1", "asia-southeast1")
+
+				req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+				var got *model.LLMResponse
+				var err error
+				for resp, respErr := range m.GenerateContent(t.Context(), req, stream) {
+					got, err = resp, respErr
+					if respErr != nil {
+						break
+					}
+				}
+
+				if diff := cmp.Diff(tt.wantRegions, regions); diff != "" {
+					t.Errorf("regions called mismatch (-want +got):\n%s", diff)
+				}
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("GenerateContent() error = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GenerateContent() error = %v", err)
+				}
+				if text := got.Content.Parts[0].Text; text != "ok" {
+					t.Errorf("final text = %q, want %q", text, "ok")
+				}
+			})
+		}
+	}
+}
+
+func TestNewModel_VertexFallbackRegions(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", writeServiceAccountKey(t, "my-project"))
+
+	llm, err := NewModel(t.Context(), "claude-sonnet-4@20250514", &Config{
+		Variant:               VariantVertexAI,
+		VertexProjectID:       "my-project",
+		VertexRegion:          "us-east5",
+		VertexFallbackRegions: []string{"europe-west1", "asia-southeast1"},
+		DisableClientSharing:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+
+	m := llm.(*anthropicModel)
+	var regions []string
+	for _, fallback := range m.fallbacks {
+		if fallback.client == nil || fallback.client == m.client {
+			t.Errorf("fallback %s has no client of its own", fallback.region)
+		}
+		regions = append(regions, fallback.region)
+	}
+	if diff := cmp.Diff([]string{"europe-west1", "asia-southeast1"}, regions); diff != "" {
+		t.Errorf("fallback regions mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// ErrNoFakeResponses is returned by a FakeModel once all of its responses
+// have been used.
+var ErrNoFakeResponses = errors.New("fake model has no responses left")
+
+// FakeModel is a [model.LLM] that returns canned responses without calling
+// Anthropic, for unit testing agents deterministically. Create one with
+// NewFakeModel. It is safe for concurrent use.
+type FakeModel struct {
+	mu        sync.Mutex
+	responses []*model.LLMResponse
+	requests  []*model.LLMRequest
+}
+
+var _ model.LLM = (*FakeModel)(nil)
+
+// NewFakeModel returns a FakeModel that answers successive GenerateContent
+// calls with the given responses, in order. Once they are used up, calls fail
+// with ErrNoFakeResponses.
+func NewFakeModel(responses ...*model.LLMResponse) *FakeModel {
+	return &FakeModel{responses: responses}
+}
+
+// Name implements model.LLM.
+func (f *FakeModel) Name() string {
+	return "fake-anthropic"
+}
+
+// Requests returns the requests received so far, in order.
+func (f *FakeModel) Requests() []*model.LLMRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*model.LLMRequest(nil), f.requests...)
+}
+
+// GenerateContent implements model.LLM. It records req and returns the next
+// canned response, with TurnComplete set. When streaming, the text of the
+// response is first yielded as a partial response, like a single streamed
+// delta.
+func (f *FakeModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		if len(f.responses) == 0 {
+			f.mu.Unlock()
+			yield(nil, ErrNoFakeResponses)
+			return
+		}
+		resp := f.responses[0]
+		f.responses = f.responses[1:]
+		f.mu.Unlock()
+
+		final := *resp
+		final.TurnComplete = true
+		if stream {
+			if partial := textPartial(resp); partial != nil {
+				if !yield(partial, nil) {
+					return
+				}
+			}
+		}
+		yield(&final, nil)
+	}
+}
+
+// textPartial returns a partial response holding the text and thought parts
+// of resp, or nil if it has none.
+func textPartial(resp *model.LLMResponse) *model.LLMResponse {
+	if resp.Content == nil {
+		return nil
+	}
+	var parts []*genai.Part
+	for _, part := range resp.Content.Parts {
+		if part != nil && part.Text != "" {
+			parts = append(parts, &genai.Part{Text: part.Text, Thought: part.Thought})
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return &model.LLMResponse{
+		Content: &genai.Content{Role: "model", Parts: parts},
+		Partial: true,
+	}
+}
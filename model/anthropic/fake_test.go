@@ -0,0 +1,76 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestFakeModel(t *testing.T) {
+	answer := &model.LLMResponse{
+		Content:      genai.NewContentFromText("Hello!", "model"),
+		FinishReason: genai.FinishReasonStop,
+	}
+	call := &model.LLMResponse{
+		Content: &genai.Content{Role: "model", Parts: []*genai.Part{
+			{FunctionCall: &genai.FunctionCall{ID: "toolu_1", Name: "lookup"}},
+		}},
+	}
+	f := NewFakeModel(answer, call, answer)
+
+	first := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	want := *answer
+	want.TurnComplete = true
+	if diff := cmp.Diff([]*model.LLMResponse{&want}, collect(t, f, first, false)); diff != "" {
+		t.Errorf("non-streaming responses mismatch (-want +got):\n%s", diff)
+	}
+
+	second := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Look it up", "user")}}
+	got := collect(t, f, second, true)
+	if len(got) != 1 || !got[0].TurnComplete || got[0].Content.Parts[0].FunctionCall == nil {
+		t.Errorf("streaming a function call = %+v, want only the final response", got)
+	}
+
+	got = collect(t, f, first, true)
+	if len(got) != 2 {
+		t.Fatalf("streaming text = %d responses, want a partial and a final response", len(got))
+	}
+	if !got[0].Partial || got[0].Content.Parts[0].Text != "Hello!" {
+		t.Errorf("partial = %+v, want the text", got[0])
+	}
+	if got[1].Partial || !got[1].TurnComplete || got[1].FinishReason != genai.FinishReasonStop {
+		t.Errorf("final = %+v, want the complete response", got[1])
+	}
+	if answer.TurnComplete {
+		t.Error("streaming modified the canned response")
+	}
+
+	for _, err := range f.GenerateContent(t.Context(), first, false) {
+		if !errors.Is(err, ErrNoFakeResponses) {
+			t.Errorf("GenerateContent() error = %v, want ErrNoFakeResponses", err)
+		}
+	}
+
+	reqs := f.Requests()
+	if len(reqs) != 4 || reqs[0] != first || reqs[1] != second {
+		t.Errorf("Requests() = %v, want the 4 requests in order", reqs)
+	}
+}
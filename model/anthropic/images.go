@@ -0,0 +1,190 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+)
+
+// Default image limits of the Messages API.
+const (
+	defaultMaxImagesPerRequest = 100
+	defaultMaxImageBytes       = 5 << 20
+	defaultMaxTotalImageBytes  = 24 << 20
+)
+
+// imageLimit returns the configured limit, the default if it is zero, or 0 if
+// the check is disabled.
+func imageLimit(configured, def int) int {
+	switch {
+	case configured < 0:
+		return 0
+	case configured == 0:
+		return def
+	default:
+		return configured
+	}
+}
+
+// checkImageLimits returns an error naming the first inline image in contents
+// that exceeds the image limits in cfg.
+func checkImageLimits(contents []*genai.Content, cfg *Config) error {
+	maxCount := imageLimit(cfg.MaxImagesPerRequest, defaultMaxImagesPerRequest)
+	maxBytes := imageLimit(cfg.MaxImageBytes, defaultMaxImageBytes)
+	maxTotal := imageLimit(cfg.MaxTotalImageBytes, defaultMaxTotalImageBytes)
+
+	count, total := 0, 0
+	for i, content := range contents {
+		if content == nil {
+			continue
+		}
+		for j, part := range content.Parts {
+			if part == nil || part.InlineData == nil || !strings.HasPrefix(converters.BaseMIMEType(part.InlineData.MIMEType), "image/") {
+				continue
+			}
+			size := len(part.InlineData.Data)
+			count++
+			total += size
+			switch {
+			case maxCount > 0 && count > maxCount:
+				return fmt.Errorf("image at contents[%d].parts[%d] exceeds the limit of %d images per request", i, j, maxCount)
+			case maxBytes > 0 && size > maxBytes:
+				return fmt.Errorf("image at contents[%d].parts[%d] is %d bytes, exceeding the limit of %d bytes per image", i, j, size, maxBytes)
+			case maxTotal > 0 && total > maxTotal:
+				return fmt.Errorf("image at contents[%d].parts[%d] brings the total image size to %d bytes, exceeding the limit of %d bytes per request", i, j, total, maxTotal)
+			}
+		}
+	}
+	return nil
+}
+
+// downscaleImages returns contents with inline images larger than maxDim
+// pixels on either side scaled down to fit. Contents and parts that change are
+// copied; the caller's contents are not modified.
+func downscaleImages(contents []*genai.Content, maxDim int) []*genai.Content {
+	result := make([]*genai.Content, len(contents))
+	for i, content := range contents {
+		result[i] = content
+		if content == nil {
+			continue
+		}
+		for j, part := range content.Parts {
+			if part == nil || part.InlineData == nil {
+				continue
+			}
+			data, ok := downscaleImage(part.InlineData.Data, part.InlineData.MIMEType, maxDim)
+			if !ok {
+				continue
+			}
+			if result[i] == content {
+				copied := *content
+				copied.Parts = append([]*genai.Part(nil), content.Parts...)
+				result[i] = &copied
+			}
+			blob := *part.InlineData
+			blob.Data = data
+			p := *part
+			p.InlineData = &blob
+			result[i].Parts[j] = &p
+		}
+	}
+	return result
+}
+
+// downscaleImage scales a JPEG, PNG or single-frame GIF image down so that
+// neither side exceeds maxDim, preserving its aspect ratio, and re-encodes it
+// in its original format. It reports false if the image is not modified:
+// because it already fits, its format is not supported, or it fails to decode.
+func downscaleImage(data []byte, mimeType string, maxDim int) ([]byte, bool) {
+	var encode func(*bytes.Buffer, image.Image) error
+	switch converters.BaseMIMEType(mimeType) {
+	case "image/jpeg":
+		encode = func(w *bytes.Buffer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+		}
+	case "image/png":
+		encode = func(w *bytes.Buffer, img image.Image) error { return png.Encode(w, img) }
+	case "image/gif":
+		// Scaling only the first frame would drop the animation
+		if g, err := gif.DecodeAll(bytes.NewReader(data)); err != nil || len(g.Image) != 1 {
+			return nil, false
+		}
+		encode = func(w *bytes.Buffer, img image.Image) error { return gif.Encode(w, img, nil) }
+	default:
+		return nil, false
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (cfg.Width <= maxDim && cfg.Height <= maxDim) {
+		return nil, false
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+
+	width, height := maxDim, maxDim
+	if cfg.Width > cfg.Height {
+		height = max(1, cfg.Height*maxDim/cfg.Width)
+	} else {
+		width = max(1, cfg.Width*maxDim/cfg.Height)
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, scaleDown(src, width, height)); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// scaleDown resizes src to width x height by averaging the source pixels
+// covered by each destination pixel.
+func scaleDown(src image.Image, width, height int) image.Image {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/height)
+		for x := range width {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/width)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.Set(x, y, color.NRGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
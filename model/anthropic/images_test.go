@@ -0,0 +1,142 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// testImage returns a width x height image encoded in the given format.
+func testImage(t *testing.T, mimeType string, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	switch mimeType {
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownscaleImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		mimeType   string
+		data       []byte
+		wantFormat string
+		wantWidth  int
+		wantHeight int
+	}{
+		{name: "png_landscape", mimeType: "image/png", data: testImage(t, "image/png", 400, 200), wantFormat: "png", wantWidth: 100, wantHeight: 50},
+		{name: "jpeg_portrait", mimeType: "image/jpeg", data: testImage(t, "image/jpeg", 150, 300), wantFormat: "jpeg", wantWidth: 50, wantHeight: 100},
+		{name: "mime_type_with_parameters", mimeType: "Image/PNG; name=photo.png", data: testImage(t, "image/png", 200, 200), wantFormat: "png", wantWidth: 100, wantHeight: 100},
+		{name: "fits", mimeType: "image/png", data: testImage(t, "image/png", 80, 60), wantFormat: "png", wantWidth: 80, wantHeight: 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := &genai.Content{Role: "user", Parts: []*genai.Part{
+				genai.NewPartFromText("Describe this"),
+				genai.NewPartFromBytes(tt.data, tt.mimeType),
+			}}
+
+			got := downscaleImages([]*genai.Content{original}, 100)
+
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(got[0].Parts[1].InlineData.Data))
+			if err != nil {
+				t.Fatalf("failed to decode result: %v", err)
+			}
+			if format != tt.wantFormat || cfg.Width != tt.wantWidth || cfg.Height != tt.wantHeight {
+				t.Errorf("result = %s %dx%d, want %s %dx%d", format, cfg.Width, cfg.Height, tt.wantFormat, tt.wantWidth, tt.wantHeight)
+			}
+			if !bytes.Equal(original.Parts[1].InlineData.Data, tt.data) {
+				t.Error("downscaleImages() modified the caller's content")
+			}
+		})
+	}
+}
+
+func TestDownscaleImages_UndecodablePassesThrough(t *testing.T) {
+	data := []byte("not an image")
+	contents := []*genai.Content{{Role: "user", Parts: []*genai.Part{genai.NewPartFromBytes(data, "image/png")}}}
+
+	got := downscaleImages(contents, 100)
+	if got[0] != contents[0] {
+		t.Error("downscaleImages() copied content with an undecodable image, want it unchanged")
+	}
+}
+
+func TestCheckImageLimits(t *testing.T) {
+	// images returns a user content with n inline images of size bytes each.
+	images := func(n, size int) []*genai.Content {
+		content := &genai.Content{Role: "user", Parts: []*genai.Part{genai.NewPartFromText("Compare these")}}
+		for range n {
+			content.Parts = append(content.Parts, genai.NewPartFromBytes(make([]byte, size), "image/png"))
+		}
+		return []*genai.Content{content}
+	}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		contents []*genai.Content
+		wantErr  string
+	}{
+		{name: "default_count_at_limit", contents: images(100, 10)},
+		{name: "default_count_over_limit", contents: images(101, 10), wantErr: "image at contents[0].parts[101] exceeds the limit of 100 images per request"},
+		{name: "count_at_limit", cfg: Config{MaxImagesPerRequest: 2}, contents: images(2, 10)},
+		{name: "count_over_limit", cfg: Config{MaxImagesPerRequest: 2}, contents: images(3, 10), wantErr: "image at contents[0].parts[3] exceeds the limit of 2 images"},
+		{name: "count_disabled", cfg: Config{MaxImagesPerRequest: -1}, contents: images(101, 10)},
+		{name: "size_at_limit", cfg: Config{MaxImageBytes: 100}, contents: images(1, 100)},
+		{name: "size_over_limit", cfg: Config{MaxImageBytes: 100}, contents: images(1, 101), wantErr: "image at contents[0].parts[1] is 101 bytes, exceeding the limit of 100 bytes per image"},
+		{name: "total_at_limit", cfg: Config{MaxTotalImageBytes: 300}, contents: images(3, 100)},
+		{name: "mime_type_case_and_parameters", cfg: Config{MaxImageBytes: 100}, contents: []*genai.Content{{Role: "user", Parts: []*genai.Part{genai.NewPartFromBytes(make([]byte, 101), "IMAGE/PNG; name=photo.png")}}}, wantErr: "image at contents[0].parts[0] is 101 bytes"},
+		{name: "total_over_limit", cfg: Config{MaxTotalImageBytes: 300}, contents: images(4, 100), wantErr: "image at contents[0].parts[4] brings the total image size to 400 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkImageLimits(tt.contents, &tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkImageLimits() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkImageLimits() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
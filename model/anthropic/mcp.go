@@ -0,0 +1,74 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// mcpClientBeta is the beta flag required by the MCP connector.
+const mcpClientBeta = anthropic.AnthropicBetaMCPClient2025_04_04
+
+// MCPServerConfig configures a remote MCP server that Anthropic connects to
+// directly, letting Claude call its tools without a local proxy.
+//
+// Calls to MCP tools and their results are executed by Anthropic. They are
+// reported in the response as [ServerToolBlockMIMEType] parts rather than as
+// function calls, so the agent does not try to execute them, and are sent back
+// as mcp_tool_use and mcp_tool_result blocks in later requests.
+type MCPServerConfig struct {
+	// URL is the URL of the MCP server. It must use HTTPS. Required.
+	URL string
+	// Name identifies the server in the model's tool calls. Required.
+	Name string
+	// AuthorizationToken is an optional OAuth bearer token for the server.
+	AuthorizationToken string
+	// AllowedTools restricts the server's tools that Claude may call.
+	// If empty, all tools are allowed.
+	AllowedTools []string
+}
+
+// validate checks that the required fields are set.
+func (s *MCPServerConfig) validate() error {
+	if s.URL == "" || s.Name == "" {
+		return fmt.Errorf("invalid MCP server %q: URL and Name are required", s.Name)
+	}
+	return nil
+}
+
+// mcpServersParam returns the mcp_servers request field for servers.
+func mcpServersParam(servers []MCPServerConfig) []map[string]any {
+	result := make([]map[string]any, 0, len(servers))
+	for _, s := range servers {
+		server := map[string]any{
+			"type": "url",
+			"url":  s.URL,
+			"name": s.Name,
+		}
+		if s.AuthorizationToken != "" {
+			server["authorization_token"] = s.AuthorizationToken
+		}
+		if len(s.AllowedTools) > 0 {
+			server["tool_configuration"] = map[string]any{
+				"enabled":       true,
+				"allowed_tools": s.AllowedTools,
+			}
+		}
+		result = append(result, server)
+	}
+	return result
+}
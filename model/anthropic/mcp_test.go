@@ -0,0 +1,155 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+)
+
+func TestMCPServers(t *testing.T) {
+	var gotBody, gotBeta string
+	cfg := &Config{
+		ComputerUse: &ComputerUse{DisplayWidthPx: 1024, DisplayHeightPx: 768},
+		MCPServers: []MCPServerConfig{{
+			URL:                "https://mcp.example.com/sse",
+			Name:               "example",
+			AuthorizationToken: "secret",
+			AllowedTools:       []string{"echo"},
+		}},
+	}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotBeta = r.Header.Get("anthropic-beta")
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	collect(t, m, req, false)
+
+	if want := computerUseBeta + "," + mcpClientBeta; gotBeta != want {
+		t.Errorf("anthropic-beta = %q, want %q", gotBeta, want)
+	}
+	want := `"mcp_servers":[{"authorization_token":"secret","name":"example","tool_configuration":{"allowed_tools":["echo"],"enabled":true},"type":"url","url":"https://mcp.example.com/sse"}]`
+	if !strings.Contains(gotBody, want) {
+		t.Errorf("request body = %s, want %s", gotBody, want)
+	}
+}
+
+func TestNewModel_InvalidMCPServer(t *testing.T) {
+	_, err := NewModel(t.Context(), "claude-sonnet-4-20250514", &Config{APIKey: "test-api-key", MCPServers: []MCPServerConfig{{Name: "example"}}})
+	if err == nil || !strings.Contains(err.Error(), "invalid MCP server") {
+		t.Fatalf("NewModel() error = %v, want invalid MCP server", err)
+	}
+}
+
+func TestMCPToolBlocks_Agent(t *testing.T) {
+	const (
+		mcpToolUse    = `{"type":"mcp_tool_use","id":"mcptoolu_1","name":"echo","server_name":"example","input":{"param1":"hi"}}`
+		mcpToolResult = `{"type":"mcp_tool_result","tool_use_id":"mcptoolu_1","is_error":false,"content":[{"type":"text","text":"hi"}]}`
+	)
+	message := `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[` +
+		mcpToolUse + `,` + mcpToolResult + `,{"type":"text","text":"The echo said hi."}],` +
+		`"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`
+	streamEvents := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"mcp_tool_use","id":"mcptoolu_1","name":"echo","server_name":"example","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"param1\":\"hi\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":` + mcpToolResult + `}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"The echo said hi."}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":15}}`,
+		`{"type":"message_stop"}`,
+	}
+
+	for _, mode := range []agent.StreamingMode{agent.StreamingModeNone, agent.StreamingModeSSE} {
+		t.Run(string(mode), func(t *testing.T) {
+			var bodies []string
+			cfg := &Config{MCPServers: []MCPServerConfig{{URL: "https://mcp.example.com/sse", Name: "example"}}}
+			m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				if strings.Contains(string(b), `"stream":true`) {
+					writeSSE(w, streamEvents...)
+				} else {
+					writeJSON(w, message)
+				}
+			})
+
+			a, err := llmagent.New(llmagent.Config{Name: "assistant", Model: m})
+			if err != nil {
+				t.Fatalf("llmagent.New() error = %v", err)
+			}
+			sessions := session.InMemoryService()
+			r, err := runner.New(runner.Config{AppName: "app", Agent: a, SessionService: sessions})
+			if err != nil {
+				t.Fatalf("runner.New() error = %v", err)
+			}
+			if _, err := sessions.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session"}); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			// The MCP tool call was executed by Anthropic, so the agent must
+			// not try to execute it, and it is sent back in the next turn.
+			for _, text := range []string{"Echo hi", "Thanks"} {
+				for _, err := range r.Run(t.Context(), "user", "session", genai.NewContentFromText(text, genai.RoleUser), agent.RunConfig{StreamingMode: mode}) {
+					if err != nil {
+						t.Fatalf("Run() error = %v", err)
+					}
+				}
+			}
+
+			if len(bodies) != 2 {
+				t.Fatalf("got %d requests, want 2", len(bodies))
+			}
+			var body struct {
+				Messages []struct {
+					Role    string            `json:"role"`
+					Content []json.RawMessage `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.Unmarshal([]byte(bodies[1]), &body); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if len(body.Messages) != 3 || body.Messages[1].Role != "assistant" || len(body.Messages[1].Content) != 3 {
+				t.Fatalf("messages = %s, want the MCP blocks and text in the assistant turn", bodies[1])
+			}
+			for i, want := range []string{mcpToolUse, mcpToolResult} {
+				var gotBlock, wantBlock any
+				_ = json.Unmarshal(body.Messages[1].Content[i], &gotBlock)
+				_ = json.Unmarshal([]byte(want), &wantBlock)
+				if diff := cmp.Diff(wantBlock, gotBlock); diff != "" {
+					t.Errorf("content[%d] mismatch (-want +got):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
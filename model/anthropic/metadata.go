@@ -0,0 +1,92 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"google.golang.org/adk/internal/anthropicllm/converters"
+)
+
+// Anthropic-specific finish reasons reported in [model.LLMResponse.FinishReason].
+const (
+	// FinishReasonPauseTurn indicates that Anthropic paused a long-running turn.
+	// Resend the request with the paused response appended to continue the turn
+	// rather than treating it as a final answer.
+	FinishReasonPauseTurn = converters.FinishReasonPauseTurn
+)
+
+// Keys of Anthropic-specific entries in [model.LLMResponse] CustomMetadata.
+const (
+	// MetadataKeyStopSequence holds the matched stop sequence (string) when
+	// generation ended on one of the configured stop sequences.
+	MetadataKeyStopSequence = converters.MetadataKeyStopSequence
+
+	// MetadataKeyServiceTier holds the service tier (string) that served the
+	// request, such as "standard" or "priority".
+	MetadataKeyServiceTier = converters.MetadataKeyServiceTier
+
+	// MetadataKeyWebSearchRequests holds the number of web searches (int64)
+	// Anthropic ran for the web search tool, which are billed per request.
+	// It is only set when at least one search was made.
+	MetadataKeyWebSearchRequests = converters.MetadataKeyWebSearchRequests
+
+	// MetadataKeyModel holds the name of the model (string) that served the
+	// request. When the model was requested by an alias such as
+	// "claude-sonnet-4-5", this is the dated version the alias resolved to.
+	MetadataKeyModel = converters.MetadataKeyModel
+
+	// MetadataKeyTruncatedToolCall holds the ID (string) of the function
+	// call cut off by the maximum number of output tokens. Its Args hold the
+	// partial input under RawToolInputKey instead of arguments that may be
+	// incomplete, and the response's FinishReason is FinishReasonMaxTokens;
+	// retry with a larger MaxOutputTokens.
+	MetadataKeyTruncatedToolCall = converters.MetadataKeyTruncatedToolCall
+
+	// MetadataKeyRequestedModel holds the name of the model (string) the
+	// request asked for. It is only set when the model that served the
+	// request, under MetadataKeyModel, is a different one, and the first
+	// such response of a model is also logged.
+	MetadataKeyRequestedModel = converters.MetadataKeyRequestedModel
+
+	// MetadataKeyContainer holds the ID (string) of the code execution
+	// container used by the request. Pass it to [WithContainer] on the next
+	// request to reuse the container and its files.
+	MetadataKeyContainer = converters.MetadataKeyContainer
+
+	// MetadataKeyCacheCreationTokens holds the number of input tokens (int64)
+	// written to the prompt cache, which are billed at a higher rate. It is
+	// only set when some were. UsageMetadata.PromptTokenCount includes them.
+	MetadataKeyCacheCreationTokens = converters.MetadataKeyCacheCreationTokens
+
+	// MetadataKeyRateLimit holds a *RateLimit parsed from the response's
+	// anthropic-ratelimit-* headers.
+	MetadataKeyRateLimit = "anthropic:rate_limit"
+)
+
+// RawToolInputKey is the genai.FunctionCall Args key that holds the raw tool
+// input when the model produced input that is not a JSON object. Responses
+// containing such a call report genai.FinishReasonMalformedFunctionCall.
+const RawToolInputKey = converters.RawToolInputKey
+
+// FunctionResponsePartsKey is the genai.FunctionResponse Response key for tool
+// results made of several content blocks. When it is the only key and holds a
+// []*genai.Part, the parts (text, images, documents and search results) are
+// sent as the content blocks of the tool result, in order, instead of a single
+// JSON text block:
+//
+//	Response: map[string]any{anthropic.FunctionResponsePartsKey: []*genai.Part{
+//		genai.NewPartFromText("Sales grew 12% in Q3."),
+//		genai.NewPartFromBytes(chartPNG, "image/png"),
+//	}}
+const FunctionResponsePartsKey = converters.FunctionResponsePartsKey
@@ -0,0 +1,99 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"google.golang.org/adk/model"
+)
+
+// MetricsRecorder receives measurements of completed model calls, for example
+// to feed token usage and latency counters and histograms.
+//
+// RecordCall is called once per call to GenerateContent, after the final
+// response or error has been yielded. It must be safe for concurrent use.
+type MetricsRecorder interface {
+	RecordCall(ctx context.Context, metrics CallMetrics)
+}
+
+// CallMetrics describes a completed model call.
+type CallMetrics struct {
+	// Model is the name of the model that was called.
+	Model string
+	// Stream reports whether the call was streaming.
+	Stream bool
+	// Latency is the time from the start of the call to its final response or error.
+	Latency time.Duration
+	// InputTokens is the number of uncached input tokens.
+	InputTokens int
+	// OutputTokens is the number of generated tokens.
+	OutputTokens int
+	// CachedInputTokens is the number of input tokens read from the prompt cache.
+	CachedInputTokens int
+	// CacheCreationInputTokens is the number of input tokens written to the
+	// prompt cache.
+	CacheCreationInputTokens int
+	// Err is the error the call failed with, or nil on success. Calls answered
+	// with a response that has an ErrorCode, such as ErrorCodeInvalidMedia,
+	// are failures too, and Err describes that response.
+	Err error
+}
+
+// noopMetricsRecorder is the MetricsRecorder used when none is configured.
+type noopMetricsRecorder struct{}
+
+func (noopMetricsRecorder) RecordCall(context.Context, CallMetrics) {}
+
+// metricsRecorder returns the configured MetricsRecorder, or a no-op recorder.
+func (m *anthropicModel) metricsRecorder() MetricsRecorder {
+	if m.cfg.MetricsRecorder != nil {
+		return m.cfg.MetricsRecorder
+	}
+	return noopMetricsRecorder{}
+}
+
+// metered records the metrics of the model call made by seq.
+func (m *anthropicModel) metered(ctx context.Context, stream bool, seq iter.Seq2[*model.LLMResponse, error]) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		metrics := CallMetrics{Model: string(m.name), Stream: stream}
+		start := time.Now()
+		defer func() {
+			metrics.Latency = time.Since(start)
+			m.metricsRecorder().RecordCall(ctx, metrics)
+		}()
+
+		for resp, err := range seq {
+			switch {
+			case err != nil:
+				metrics.Err = err
+			case resp.ErrorCode != "":
+				metrics.Err = fmt.Errorf("%s: %s", resp.ErrorCode, resp.ErrorMessage)
+			case !resp.Partial && resp.UsageMetadata != nil:
+				cacheCreation, _ := resp.CustomMetadata[MetadataKeyCacheCreationTokens].(int64)
+				metrics.CachedInputTokens = int(resp.UsageMetadata.CachedContentTokenCount)
+				metrics.CacheCreationInputTokens = int(cacheCreation)
+				metrics.InputTokens = int(resp.UsageMetadata.PromptTokenCount) - metrics.CachedInputTokens - metrics.CacheCreationInputTokens
+				metrics.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount + resp.UsageMetadata.ThoughtsTokenCount)
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// fakeMetricsRecorder records the metrics of every call.
+type fakeMetricsRecorder struct {
+	calls []CallMetrics
+}
+
+func (r *fakeMetricsRecorder) RecordCall(_ context.Context, metrics CallMetrics) {
+	r.calls = append(r.calls, metrics)
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			recorder := &fakeMetricsRecorder{}
+			m := newTestModel(t, &Config{MetricsRecorder: recorder}, func(w http.ResponseWriter, r *http.Request) {
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			collect(t, m, req, stream)
+
+			if len(recorder.calls) != 1 {
+				t.Fatalf("got %d recorded calls, want 1", len(recorder.calls))
+			}
+			got := recorder.calls[0]
+			if got.Model != "claude-sonnet-4-20250514" || got.Stream != stream {
+				t.Errorf("Model, Stream = %q, %v, want model name, %v", got.Model, got.Stream, stream)
+			}
+			if got.InputTokens != 25 || got.OutputTokens != 15 {
+				t.Errorf("InputTokens, OutputTokens = %d, %d, want 25, 15", got.InputTokens, got.OutputTokens)
+			}
+			if got.Latency <= 0 || got.Err != nil {
+				t.Errorf("Latency, Err = %v, %v, want positive latency and no error", got.Latency, got.Err)
+			}
+		})
+	}
+}
+
+func TestMetricsRecorder_Error(t *testing.T) {
+	recorder := &fakeMetricsRecorder{}
+	m := newTestModel(t, &Config{MetricsRecorder: recorder}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	for range m.GenerateContent(t.Context(), req, false) {
+	}
+
+	if len(recorder.calls) != 1 || recorder.calls[0].Err == nil {
+		t.Errorf("recorded calls = %+v, want one call with an error", recorder.calls)
+	}
+}
+
+func TestMetricsRecorder_CacheTokens(t *testing.T) {
+	recorder := &fakeMetricsRecorder{}
+	m := newTestModel(t, &Config{MetricsRecorder: recorder}, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15,"cache_read_input_tokens":1200,"cache_creation_input_tokens":300}}`)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, false)
+
+	if usage := got[0].UsageMetadata; usage.PromptTokenCount != 1525 || usage.CachedContentTokenCount != 1200 {
+		t.Errorf("PromptTokenCount, CachedContentTokenCount = %d, %d, want 1525, 1200", usage.PromptTokenCount, usage.CachedContentTokenCount)
+	}
+	if n := got[0].CustomMetadata[MetadataKeyCacheCreationTokens]; n != int64(300) {
+		t.Errorf("CustomMetadata[%q] = %v, want 300", MetadataKeyCacheCreationTokens, n)
+	}
+	metrics := recorder.calls[0]
+	if metrics.InputTokens != 25 || metrics.CachedInputTokens != 1200 || metrics.CacheCreationInputTokens != 300 {
+		t.Errorf("InputTokens, CachedInputTokens, CacheCreationInputTokens = %d, %d, %d, want 25, 1200, 300",
+			metrics.InputTokens, metrics.CachedInputTokens, metrics.CacheCreationInputTokens)
+	}
+}
+
+func TestMetricsRecorder_ErrorResponse(t *testing.T) {
+	recorder := &fakeMetricsRecorder{}
+	m := newTestModel(t, &Config{MetricsRecorder: recorder}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"messages.0.content.0.image.source.base64.data: The image was specified using the image/png media type, but does not appear to be a valid png image"}}`)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, false)
+
+	if got[0].ErrorCode != ErrorCodeInvalidMedia {
+		t.Fatalf("ErrorCode = %q, want %q", got[0].ErrorCode, ErrorCodeInvalidMedia)
+	}
+	if len(recorder.calls) != 1 || recorder.calls[0].Err == nil || !strings.Contains(recorder.calls[0].Err.Error(), ErrorCodeInvalidMedia) {
+		t.Errorf("recorded calls = %+v, want one call with an INVALID_MEDIA error", recorder.calls)
+	}
+}
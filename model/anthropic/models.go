@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// modelTemperatures maps model name prefixes to the temperature sent by
+// default to the models they match, for models that are better served by a
+// temperature other than the API default of 1.0.
+var modelTemperatures = map[string]float64{
+	"claude-3-haiku":   0.7,
+	"claude-3-5-haiku": 0.7,
+}
+
+// modelTemperature returns the default temperature of the model, from
+// Config.ModelTemperatures or else modelTemperatures, matching the longest
+// prefix of the model name.
+func (m *anthropicModel) modelTemperature() (float64, bool) {
+	for _, table := range []map[string]float64{m.cfg.ModelTemperatures, modelTemperatures} {
+		var temperature float64
+		match := ""
+		for prefix, t := range table {
+			if strings.HasPrefix(string(m.name), prefix) && len(prefix) > len(match) {
+				temperature, match = t, prefix
+			}
+		}
+		if match != "" {
+			return temperature, true
+		}
+	}
+	return 0, false
+}
+
+// ModelInfo describes a model available to the configured credentials.
+type ModelInfo struct {
+	// ID is the model name to pass to NewModel.
+	ID string
+	// DisplayName is a human-readable name for the model.
+	DisplayName string
+	// CreatedAt is the model's release time. It may be the zero Unix time if
+	// the release date is unknown.
+	CreatedAt time.Time
+}
+
+// ListModels returns the models available with cfg, most recently released
+// first. The client is set up as by NewModel, so the same credentials and
+// environment variables apply. Listing models is only supported by the
+// direct Anthropic API, not by Vertex AI.
+func ListModels(ctx context.Context, cfg *Config) ([]ModelInfo, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if cmp.Or(cfg.Variant, GetVariant()) == VariantVertexAI {
+		return nil, fmt.Errorf("listing models is not supported on Vertex AI")
+	}
+	client, _, err := newClient(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var models []ModelInfo
+	iter := client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+	for iter.Next() {
+		info := iter.Current()
+		models = append(models, ModelInfo{
+			ID:          info.ID,
+			DisplayName: info.DisplayName,
+			CreatedAt:   info.CreatedAt,
+		})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	return models, nil
+}
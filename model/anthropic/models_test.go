@@ -0,0 +1,59 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("after_id") == "" {
+			writeJSON(w, `{"data":[{"id":"claude-opus-4-5-20251101","type":"model","display_name":"Claude Opus 4.5","created_at":"2025-11-01T00:00:00Z"}],"has_more":true,"first_id":"claude-opus-4-5-20251101","last_id":"claude-opus-4-5-20251101"}`)
+			return
+		}
+		writeJSON(w, `{"data":[{"id":"claude-haiku-4-5-20251001","type":"model","display_name":"Claude Haiku 4.5","created_at":"2025-10-01T00:00:00Z"}],"has_more":false,"first_id":"claude-haiku-4-5-20251001","last_id":"claude-haiku-4-5-20251001"}`)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("ANTHROPIC_BASE_URL", srv.URL)
+
+	got, err := ListModels(t.Context(), &Config{APIKey: "test-api-key", Variant: VariantAnthropicAPI, DisableClientSharing: true})
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	want := []ModelInfo{
+		{ID: "claude-opus-4-5-20251101", DisplayName: "Claude Opus 4.5", CreatedAt: time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "claude-haiku-4-5-20251001", DisplayName: "Claude Haiku 4.5", CreatedAt: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListModels() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestListModels_VertexAI(t *testing.T) {
+	cfg := &Config{Variant: VariantVertexAI, VertexProjectID: "my-project", VertexRegion: "us-east5", DisableClientSharing: true}
+	if _, err := ListModels(t.Context(), cfg); err == nil {
+		t.Error("ListModels() error = nil, want an error on Vertex AI")
+	}
+}
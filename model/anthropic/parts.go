@@ -0,0 +1,120 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+)
+
+// ErrUnsupportedContent is wrapped by the errors returned when a request holds
+// content that Anthropic models cannot read, such as video, rather than
+// dropping it silently. Use errors.Is to detect it.
+var ErrUnsupportedContent = converters.ErrUnsupportedContent
+
+// DocumentChunksMIMEType is the MIME type of inline data parts holding a
+// pre-chunked document: a JSON array of strings, one per chunk.
+// Use [NewDocumentChunksPart] to build such parts.
+const DocumentChunksMIMEType = converters.DocumentChunksMIMEType
+
+// NewDocumentChunksPart returns a part that is sent to Claude as a custom
+// content document made of the given chunks, with citations enabled.
+//
+// Citations to the document are reported with StartIndex and EndIndex set to
+// chunk indices (EndIndex is exclusive), which makes it easy to map them back
+// to the passages retrieved for RAG.
+func NewDocumentChunksPart(title string, chunks ...string) *genai.Part {
+	data, _ := json.Marshal(chunks) // marshalling []string cannot fail
+	return &genai.Part{
+		InlineData: &genai.Blob{
+			Data:        data,
+			MIMEType:    DocumentChunksMIMEType,
+			DisplayName: title,
+		},
+	}
+}
+
+// SearchResultMIMEType is the MIME type of inline data parts holding a search
+// result. Use [NewSearchResultPart] to build such parts.
+const SearchResultMIMEType = converters.SearchResultMIMEType
+
+// NewSearchResultPart returns a part that is sent to Claude as a search_result
+// block, with citations enabled. source identifies where the passages were
+// retrieved from, such as a URL. Search results can be sent in user messages
+// or returned by tools, with [FunctionResponsePartsKey].
+//
+// Citations to the result are reported with URI set to source, Title set to
+// title, and StartIndex and EndIndex set to passage indices (EndIndex is
+// exclusive).
+func NewSearchResultPart(source, title string, passages ...string) *genai.Part {
+	data, _ := json.Marshal(converters.SearchResult{Source: source, Title: title, Content: passages}) // cannot fail
+	return &genai.Part{
+		InlineData: &genai.Blob{
+			Data:     data,
+			MIMEType: SearchResultMIMEType,
+		},
+	}
+}
+
+// CacheBreakpointMIMEType is the MIME type of the marker parts built by
+// [NewCacheBreakpointPart].
+const CacheBreakpointMIMEType = converters.CacheBreakpointMIMEType
+
+// NewCacheBreakpointPart returns a marker part that places a prompt cache
+// breakpoint in a system instruction. The system text up to and including the
+// part before the marker is cached, while parts after it are not, so a large
+// static preamble can be cached ahead of a small dynamic tail:
+//
+//	SystemInstruction: &genai.Content{Parts: []*genai.Part{
+//		genai.NewPartFromText(preamble),
+//		anthropic.NewCacheBreakpointPart(),
+//		genai.NewPartFromText(dynamicInstructions),
+//	}}
+//
+// Anthropic accepts at most 4 breakpoints per request; requests with more fail
+// with an error. Prefixes shorter than the model's minimum cacheable length
+// are not cached.
+func NewCacheBreakpointPart() *genai.Part {
+	return &genai.Part{InlineData: &genai.Blob{MIMEType: CacheBreakpointMIMEType}}
+}
+
+// ServerToolBlockMIMEType is the MIME type of the parts that report content
+// blocks of tools executed by Anthropic, such as calls to the tools of an
+// [MCPServerConfig] or to the code execution tool, and their results. Data holds the JSON of the block. Keep
+// such parts in the history: they are sent back to Claude unchanged.
+const ServerToolBlockMIMEType = converters.ServerToolBlockMIMEType
+
+// ImagePartFromFile reads the image at path and returns it as an inline data
+// part. The MIME type is detected from the file's content, and the image must
+// be a JPEG, PNG, GIF or WebP image, the formats Claude accepts.
+func ImagePartFromFile(path string) (*genai.Part, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mimeType := http.DetectContentType(data)
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return genai.NewPartFromBytes(data, mimeType), nil
+	default:
+		return nil, fmt.Errorf("%s is not a JPEG, PNG, GIF or WebP image (detected %s)", path, mimeType)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImagePartFromFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		wantMIME string
+		wantErr  string
+	}{
+		{name: "png", path: write("chart.png", testImage(t, "image/png", 4, 4)), wantMIME: "image/png"},
+		{name: "jpeg_wrong_extension", path: write("photo.png", testImage(t, "image/jpeg", 4, 4)), wantMIME: "image/jpeg"},
+		{name: "not_an_image", path: write("notes.png", []byte("just some text")), wantErr: "is not a JPEG, PNG, GIF or WebP image (detected text/plain"},
+		{name: "missing", path: filepath.Join(dir, "missing.png"), wantErr: "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			part, err := ImagePartFromFile(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ImagePartFromFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImagePartFromFile() error = %v", err)
+			}
+			if part.InlineData == nil || part.InlineData.MIMEType != tt.wantMIME || len(part.InlineData.Data) == 0 {
+				t.Errorf("ImagePartFromFile() = %+v, want %s inline data", part, tt.wantMIME)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/adk/model"
+)
+
+// RateLimit holds the rate limit state reported by the anthropic-ratelimit-*
+// response headers. It is attached to responses under MetadataKeyRateLimit.
+//
+// Fields are zero when the corresponding header is absent.
+type RateLimit struct {
+	RequestsLimit     int64
+	RequestsRemaining int64
+	RequestsReset     time.Time
+
+	TokensLimit     int64
+	TokensRemaining int64
+	TokensReset     time.Time
+
+	InputTokensLimit     int64
+	InputTokensRemaining int64
+	InputTokensReset     time.Time
+
+	OutputTokensLimit     int64
+	OutputTokensRemaining int64
+	OutputTokensReset     time.Time
+}
+
+// rateLimitFromHeader parses the anthropic-ratelimit-* headers.
+// It returns nil if none are present.
+func rateLimitFromHeader(h http.Header) *RateLimit {
+	found := false
+	parseInt := func(name string) int64 {
+		v := h.Get("anthropic-ratelimit-" + name)
+		if v == "" {
+			return 0
+		}
+		found = true
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	parseTime := func(name string) time.Time {
+		v := h.Get("anthropic-ratelimit-" + name)
+		if v == "" {
+			return time.Time{}
+		}
+		found = true
+		t, _ := time.Parse(time.RFC3339, v)
+		return t
+	}
+
+	rl := &RateLimit{
+		RequestsLimit:         parseInt("requests-limit"),
+		RequestsRemaining:     parseInt("requests-remaining"),
+		RequestsReset:         parseTime("requests-reset"),
+		TokensLimit:           parseInt("tokens-limit"),
+		TokensRemaining:       parseInt("tokens-remaining"),
+		TokensReset:           parseTime("tokens-reset"),
+		InputTokensLimit:      parseInt("input-tokens-limit"),
+		InputTokensRemaining:  parseInt("input-tokens-remaining"),
+		InputTokensReset:      parseTime("input-tokens-reset"),
+		OutputTokensLimit:     parseInt("output-tokens-limit"),
+		OutputTokensRemaining: parseInt("output-tokens-remaining"),
+		OutputTokensReset:     parseTime("output-tokens-reset"),
+	}
+	if !found {
+		return nil
+	}
+	return rl
+}
+
+// attachRateLimit records the rate limit headers of raw on resp.
+func attachRateLimit(resp *model.LLMResponse, raw *http.Response) {
+	if resp == nil || raw == nil {
+		return
+	}
+	rl := rateLimitFromHeader(raw.Header)
+	if rl == nil {
+		return
+	}
+	if resp.CustomMetadata == nil {
+		resp.CustomMetadata = make(map[string]any)
+	}
+	resp.CustomMetadata[MetadataKeyRateLimit] = rl
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// recorder is a client middleware that replays HTTP responses saved in
+// replayDir and saves the responses it gets from the API in recordDir.
+// Interactions are stored one per file, named after a hash of the request.
+type recorder struct {
+	recordDir string
+	replayDir string
+}
+
+// middleware implements option.Middleware.
+func (r *recorder) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	key, err := interactionKey(req)
+	if err != nil {
+		return nil, err
+	}
+	name := key + ".http"
+
+	if r.replayDir != "" {
+		resp, err := readInteraction(filepath.Join(r.replayDir, name), req)
+		if err == nil {
+			return resp, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to replay %s %s: %w", req.Method, req.URL.Path, err)
+		}
+		if r.recordDir == "" {
+			return missingInteraction(req, name), nil
+		}
+	}
+
+	resp, err := next(req)
+	if err != nil || r.recordDir == "" {
+		return resp, err
+	}
+	dump, err := httputil.DumpResponse(resp, true)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+	if err := os.MkdirAll(r.recordDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.recordDir, name), dump, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+}
+
+// interactionKey returns a hash of the method, path and JSON body of req.
+// Headers, which hold credentials, are not part of the key. The body of req
+// is consumed and replaced.
+func interactionKey(req *http.Request) (string, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		compact.Reset()
+		compact.Write(body)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.Path)
+	h.Write(compact.Bytes())
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// readInteraction reads the response recorded in file.
+func readInteraction(file string, req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+}
+
+// missingInteraction returns the API-style error response reported when no
+// recorded response matches req. Returning a response rather than an error
+// keeps the client from retrying the request.
+func missingInteraction(req *http.Request, name string) *http.Response {
+	body, _ := json.Marshal(map[string]any{
+		"type": "error",
+		"error": map[string]string{
+			"type":    "not_found_error",
+			"message": fmt.Sprintf("no recorded response for %s %s (%s)", req.Method, req.URL.Path, name),
+		},
+	})
+	return &http.Response{
+		StatusCode: http.StatusNotFound,
+		Status:     "404 Not Found",
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type":   {"application/json"},
+			"X-Should-Retry": {"false"},
+		},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
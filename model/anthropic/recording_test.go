@@ -0,0 +1,77 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestRecordReplay(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			dir := t.TempDir()
+			calls := 0
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if stream {
+					writeSSE(w, textStreamEvents("recorded", "end_turn")...)
+					return
+				}
+				writeJSON(w, strings.Replace(okMessage, `"text":"ok"`, `"text":"recorded"`, 1))
+			}
+			req := func() *model.LLMRequest {
+				return &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			}
+			cfg := &Config{RecordDir: dir, ReplayDir: dir, DisableClientSharing: true}
+
+			// The first run records the response
+			m := newTestModel(t, cfg, handler)
+			got := collect(t, m, req(), stream)
+			if text := got[len(got)-1].Content.Parts[0].Text; text != "recorded" {
+				t.Fatalf("recording run text = %q, want %q", text, "recorded")
+			}
+			files, _ := os.ReadDir(dir)
+			if len(files) != 1 || calls != 1 {
+				t.Fatalf("recorded %d files with %d API calls, want 1 and 1", len(files), calls)
+			}
+
+			// Later runs replay it without calling the API
+			m = newTestModel(t, &Config{ReplayDir: dir, DisableClientSharing: true}, handler)
+			got = collect(t, m, req(), stream)
+			if text := got[len(got)-1].Content.Parts[0].Text; text != "recorded" {
+				t.Errorf("replaying run text = %q, want %q", text, "recorded")
+			}
+			if calls != 1 {
+				t.Errorf("API called %d times, want the replay not to call it", calls)
+			}
+
+			// Requests that were not recorded fail in replay-only mode
+			other := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Bye", "user")}}
+			for _, err := range m.GenerateContent(t.Context(), other, stream) {
+				if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+					t.Errorf("GenerateContent() error = %v, want a replay error", err)
+				}
+			}
+		})
+	}
+}
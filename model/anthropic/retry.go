@@ -0,0 +1,163 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// RetryPolicy configures retries of failed requests using exponential backoff
+// with full jitter. It retries the failures the SDK retries by default:
+// request timeouts (HTTP 408), conflicts (HTTP 409), rate limits (HTTP 429),
+// server errors (HTTP 5xx, including overloaded 529) and connection errors,
+// unless the response says otherwise with an x-should-retry header.
+//
+// When a retry-after header is present, its delay is used instead of the
+// computed backoff. Streaming requests are only retried before any event has
+// been received.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	// If zero, defaults to 3.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry. It doubles on every
+	// subsequent retry. If zero, defaults to 500ms.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between attempts, including delays requested by
+	// a retry-after header. If zero, defaults to 30s.
+	MaxDelay time.Duration
+}
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 30 * time.Second
+)
+
+// statusOverloaded is the HTTP status Anthropic returns when the API is overloaded.
+const statusOverloaded = 529
+
+// withRetry calls call, retrying transient failures according to the
+// configured RetryPolicy.
+func (m *anthropicModel) withRetry(ctx context.Context, call func() error) error {
+	p := m.cfg.RetryPolicy
+	if p == nil {
+		return call()
+	}
+
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRetryMaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= maxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.delay(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns how long to wait before the given retry (starting at 1).
+func (p *RetryPolicy) delay(retry int, err error) time.Duration {
+	baseDelay := p.BaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+
+	if d, ok := retryAfter(err); ok {
+		return min(d, maxDelay)
+	}
+
+	backoff := baseDelay << (retry - 1)
+	if backoff <= 0 || backoff > maxDelay {
+		backoff = maxDelay
+	}
+	// Full jitter spreads out retries from concurrent callers
+	return rand.N(backoff) + 1
+}
+
+// isRetryable reports whether err is a transient failure that the SDK would
+// retry: an API error with a retryable status, or a connection error.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	if apiErr.Response != nil {
+		switch apiErr.Response.Header.Get("X-Should-Retry") {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	switch apiErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return apiErr.StatusCode >= http.StatusInternalServerError
+}
+
+// retryAfter returns the delay requested by the retry-after-ms or retry-after
+// header of an API error response.
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0, false
+	}
+
+	header := apiErr.Response.Header
+	if v := header.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs >= 0 {
+			return time.Duration(secs * float64(time.Second)), true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(time.Until(t), 0), true
+		}
+	}
+	return 0, false
+}
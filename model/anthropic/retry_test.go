@@ -0,0 +1,194 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestRetryPolicy_RetriesRateLimitedThenSucceeds(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		stream bool
+	}{
+		{"rate_limited", http.StatusTooManyRequests, false},
+		{"overloaded", statusOverloaded, false},
+		{"rate_limited_stream", http.StatusTooManyRequests, true},
+		{"overloaded_stream", statusOverloaded, true},
+		{"request_timeout", http.StatusRequestTimeout, false},
+		{"server_error", http.StatusInternalServerError, false},
+		{"server_error_stream", http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			cfg := &Config{RetryPolicy: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+			m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.status)
+					writeJSON(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+					return
+				}
+				if tt.stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, tt.stream)
+
+			if n := calls.Load(); n != 2 {
+				t.Errorf("server received %d requests, want 2", n)
+			}
+			if final := got[len(got)-1]; final.Content.Parts[0].Text != "ok" {
+				t.Errorf("final text = %q, want %q", final.Content.Parts[0].Text, "ok")
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_StopsAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	cfg := &Config{RetryPolicy: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+		writeJSON(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	for _, err := range m.GenerateContent(t.Context(), req, false) {
+		if err == nil {
+			t.Fatal("GenerateContent() error = nil, want error")
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
+
+func TestRetryPolicy_DoesNotRetryInvalidRequest(t *testing.T) {
+	var calls atomic.Int32
+	cfg := &Config{RetryPolicy: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	for _, err := range m.GenerateContent(t.Context(), req, false) {
+		if err == nil {
+			t.Fatal("GenerateContent() error = nil, want error")
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
+
+func TestRetryPolicy_HonorsShouldRetryHeader(t *testing.T) {
+	var calls atomic.Int32
+	cfg := &Config{RetryPolicy: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("X-Should-Retry", "false")
+		w.WriteHeader(http.StatusInternalServerError)
+		writeJSON(w, `{"type":"error","error":{"type":"api_error","message":"failed"}}`)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	for range m.GenerateContent(t.Context(), req, false) {
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
+
+func TestRetryPolicy_RetriesConnectionErrors(t *testing.T) {
+	var calls atomic.Int32
+	cfg := &Config{RetryPolicy: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// Drop the connection without responding
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("Hijack() error = %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		writeJSON(w, okMessage)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, false)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+	if final := got[len(got)-1]; final.Content.Parts[0].Text != "ok" {
+		t.Errorf("final text = %q, want %q", final.Content.Parts[0].Text, "ok")
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for retry, ceiling := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		3:  400 * time.Millisecond,
+		10: time.Second,
+	} {
+		for range 20 {
+			if d := p.delay(retry, nil); d <= 0 || d > ceiling {
+				t.Fatalf("delay(%d) = %v, want in (0, %v]", retry, d, ceiling)
+			}
+		}
+	}
+}
+
+func TestRetryPolicy_DelayHonorsRetryAfter(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second}
+	newErr := func(key, value string) error {
+		return &anthropic.Error{
+			StatusCode: http.StatusTooManyRequests,
+			Response:   &http.Response{Header: http.Header{key: []string{value}}},
+		}
+	}
+
+	if d := p.delay(1, newErr("Retry-After", "2")); d != 2*time.Second {
+		t.Errorf("delay(Retry-After: 2) = %v, want 2s", d)
+	}
+	if d := p.delay(1, newErr("Retry-After-Ms", "250")); d != 250*time.Millisecond {
+		t.Errorf("delay(Retry-After-Ms: 250) = %v, want 250ms", d)
+	}
+	if d := p.delay(1, newErr("Retry-After", "60")); d != 5*time.Second {
+		t.Errorf("delay(Retry-After: 60) = %v, want capped at 5s", d)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+	"google.golang.org/adk/model"
+)
+
+// deltaBuffer coalesces streamed text and thinking deltas into larger partial
+// responses. A zero deltaBuffer does not buffer: every delta is returned as
+// its own response.
+type deltaBuffer struct {
+	// maxChars flushes the buffer once it holds at least this many bytes.
+	maxChars int
+	// maxAge flushes the buffer once its oldest delta is at least this old.
+	// The age is checked as deltas arrive; no timer is involved.
+	maxAge time.Duration
+
+	text    strings.Builder
+	thought bool
+	started time.Time
+}
+
+// add buffers a delta and returns the partial responses ready to be yielded.
+// Switching between text and thinking flushes the buffered delta first.
+func (b *deltaBuffer) add(text string, thought bool) []*model.LLMResponse {
+	var ready []*model.LLMResponse
+	if b.text.Len() > 0 && b.thought != thought {
+		ready = appendFlushed(ready, b)
+	}
+	if b.text.Len() == 0 {
+		b.thought = thought
+		b.started = time.Now()
+	}
+	b.text.WriteString(text)
+
+	full := b.maxChars > 0 && b.text.Len() >= b.maxChars
+	stale := b.maxAge > 0 && time.Since(b.started) >= b.maxAge
+	if (b.maxChars == 0 && b.maxAge == 0) || full || stale {
+		ready = appendFlushed(ready, b)
+	}
+	return ready
+}
+
+// sign returns the buffered thinking, if any, as a partial response carrying
+// the signature of the thinking block, so that the signature survives in the
+// streamed partials.
+func (b *deltaBuffer) sign(signature string) []*model.LLMResponse {
+	var ready []*model.LLMResponse
+	if b.text.Len() > 0 && !b.thought {
+		ready = appendFlushed(ready, b)
+	}
+	text := b.text.String()
+	b.text.Reset()
+	return append(ready, converters.StreamThinkingSignatureToPartialResponse(text, signature))
+}
+
+// flush returns the buffered delta as a partial response and empties the
+// buffer. It returns nil if the buffer is empty.
+func (b *deltaBuffer) flush() *model.LLMResponse {
+	if b.text.Len() == 0 {
+		return nil
+	}
+	text := b.text.String()
+	b.text.Reset()
+	if b.thought {
+		return converters.StreamThinkingDeltaToPartialResponse(text)
+	}
+	return converters.StreamDeltaToPartialResponse(text)
+}
+
+// appendFlushed appends the flushed contents of b to ready, unless b is empty,
+// as it is after an empty delta.
+func appendFlushed(ready []*model.LLMResponse, b *deltaBuffer) []*model.LLMResponse {
+	if resp := b.flush(); resp != nil {
+		return append(ready, resp)
+	}
+	return ready
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// deltaStreamEvents returns the SSE events for a streamed text block made of deltas.
+func deltaStreamEvents(deltas ...string) []string {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+	}
+	for _, d := range deltas {
+		events = append(events, fmt.Sprintf(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":%q}}`, d))
+	}
+	return append(events,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":15}}`,
+		`{"type":"message_stop"}`,
+	)
+}
+
+// partialTexts returns the text of the partial responses with content.
+func partialTexts(resps []*model.LLMResponse) []string {
+	var texts []string
+	for _, resp := range resps {
+		if resp.Partial && resp.Content != nil {
+			texts = append(texts, resp.Content.Parts[0].Text)
+		}
+	}
+	return texts
+}
+
+func TestGenerateStream_Buffering(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want []string
+	}{
+		{name: "unbuffered", want: []string{"He", "ll", "o ", "wo", "rld"}},
+		{name: "chars", cfg: &Config{StreamBufferChars: 4}, want: []string{"Hell", "o wo", "rld"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, tt.cfg, func(w http.ResponseWriter, r *http.Request) {
+				writeSSE(w, deltaStreamEvents("He", "ll", "o ", "wo", "rld")...)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, true)
+
+			if diff := cmp.Diff(tt.want, partialTexts(got)); diff != "" {
+				t.Errorf("partial texts mismatch (-want +got):\n%s", diff)
+			}
+			if text := got[len(got)-1].Content.Parts[0].Text; text != "Hello world" {
+				t.Errorf("final text = %q, want %q", text, "Hello world")
+			}
+		})
+	}
+}
+
+func TestGenerateStream_EmptyDelta(t *testing.T) {
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, textStreamEvents("", "end_turn")...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, true)
+
+	for i, resp := range got {
+		if resp == nil {
+			t.Fatalf("response %d is nil", i)
+		}
+	}
+	if last := got[len(got)-1]; last.Partial || !last.TurnComplete {
+		t.Errorf("last response = %+v, want the complete turn", last)
+	}
+}
+
+func TestDeltaBuffer_EmptyDelta(t *testing.T) {
+	var buf deltaBuffer
+	if got := buf.add("", false); len(got) != 0 {
+		t.Errorf("add(\"\") = %v, want no responses", got)
+	}
+	if got := buf.add("", true); len(got) != 0 {
+		t.Errorf("add(\"\") for thinking = %v, want no responses", got)
+	}
+}
+
+func TestDeltaBuffer_SwitchesKind(t *testing.T) {
+	buf := &deltaBuffer{maxChars: 100}
+	if got := buf.add("thinking", true); len(got) != 0 {
+		t.Fatalf("add() = %d responses, want 0", len(got))
+	}
+	got := buf.add("answer", false)
+	if len(got) != 1 || !got[0].Content.Parts[0].Thought || got[0].Content.Parts[0].Text != "thinking" {
+		t.Fatalf("add() after switching to text = %v, want the buffered thought", got)
+	}
+	if resp := buf.flush(); resp == nil || resp.Content.Parts[0].Text != "answer" {
+		t.Errorf("flush() = %v, want %q", resp, "answer")
+	}
+	if resp := buf.flush(); resp != nil {
+		t.Errorf("flush() on empty buffer = %v, want nil", resp)
+	}
+}
+
+func BenchmarkGenerateStream(b *testing.B) {
+	deltas := make([]string, 1000)
+	for i := range deltas {
+		deltas[i] = "tok "
+	}
+	events := deltaStreamEvents(deltas...)
+
+	for _, bc := range []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "unbuffered"},
+		{name: "buffered_256_chars", cfg: Config{StreamBufferChars: 256}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeSSE(w, events...)
+			}))
+			defer srv.Close()
+			b.Setenv("ANTHROPIC_BASE_URL", srv.URL)
+
+			cfg := bc.cfg
+			cfg.APIKey = "test-api-key"
+			cfg.Variant = VariantAnthropicAPI
+			m, err := NewModel(b.Context(), "claude-sonnet-4-20250514", &cfg)
+			if err != nil {
+				b.Fatalf("NewModel() error = %v", err)
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+				for _, err := range m.GenerateContent(b.Context(), req, true) {
+					if err != nil {
+						b.Fatalf("GenerateContent() error = %v", err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+
+	"google.golang.org/adk/model"
+)
+
+// ErrStreamIdleTimeout reports that a stream received no data for longer than
+// [Config.StreamIdleTimeout].
+var ErrStreamIdleTimeout = errors.New("anthropic: stream idle timeout")
+
+// idleWatchdog cancels the context of a stream when no data arrives from the
+// API for longer than its timeout. Any data counts, including the ping events
+// the API sends to keep long streams alive. It is armed once a successful
+// response arrives, so the time spent waiting for the response and between
+// retries does not count, and paused while the caller handles a response.
+type idleWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	// armed reports whether a successful response has arrived. It is only
+	// accessed from the goroutine consuming the stream.
+	armed bool
+}
+
+// newIdleWatchdog returns a context derived from ctx that is canceled when the
+// watchdog fires, and a function that stops the watchdog and releases the
+// context. The watchdog is not armed until its middleware sees a response.
+func newIdleWatchdog(ctx context.Context, timeout time.Duration) (context.Context, *idleWatchdog, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &idleWatchdog{timeout: timeout}
+	w.timer = time.AfterFunc(timeout, func() {
+		cancel(fmt.Errorf("%w: no data received for %v", ErrStreamIdleTimeout, timeout))
+	})
+	w.timer.Stop()
+	return ctx, w, func() {
+		w.timer.Stop()
+		cancel(nil)
+	}
+}
+
+// touch restarts the idle timer.
+func (w *idleWatchdog) touch() {
+	w.timer.Reset(w.timeout)
+}
+
+// pausing returns yield wrapped to stop the idle timer while the caller
+// handles each response: the body is not read in the meantime, so the caller's
+// time would otherwise count as idle time of the stream.
+func (w *idleWatchdog) pausing(yield func(*model.LLMResponse, error) bool) func(*model.LLMResponse, error) bool {
+	return func(resp *model.LLMResponse, err error) bool {
+		w.timer.Stop()
+		defer func() {
+			if w.armed {
+				w.touch()
+			}
+		}()
+		return yield(resp, err)
+	}
+}
+
+// middleware arms the idle timer when a successful response arrives, and
+// restarts it each time data is read from its body. Failed attempts leave it
+// stopped, so that it does not run during the backoff before a retry.
+func (w *idleWatchdog) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	resp, err := next(req)
+	if err != nil || resp == nil || resp.StatusCode >= 300 {
+		w.armed = false
+		w.timer.Stop()
+		return resp, err
+	}
+	w.armed = true
+	w.touch()
+	if resp.Body != nil {
+		resp.Body = &activityReader{ReadCloser: resp.Body, touch: w.touch}
+	}
+	return resp, err
+}
+
+// activityReader calls touch each time data is read.
+type activityReader struct {
+	io.ReadCloser
+	touch func()
+}
+
+// Read implements io.Reader.
+func (r *activityReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.touch()
+	}
+	return n, err
+}
+
+// idleTimeoutError returns the error of a stream whose watchdog fired on ctx,
+// or nil.
+func idleTimeoutError(ctx context.Context) error {
+	if err := context.Cause(ctx); errors.Is(err, ErrStreamIdleTimeout) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// writePing writes a ping event.
+func writePing(w http.ResponseWriter) {
+	fmt.Fprint(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+	w.(http.Flusher).Flush()
+}
+
+func TestGenerateStream_IdleTimeoutPings(t *testing.T) {
+	m := newTestModel(t, &Config{StreamIdleTimeout: 200 * time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		events := textStreamEvents("Hello", "end_turn")
+		writeSSE(w, events[:2]...)
+		// Pings keep the stream alive for longer than the idle timeout
+		for range 6 {
+			time.Sleep(50 * time.Millisecond)
+			writePing(w)
+		}
+		writeSSE(w, events[2:]...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, true)
+	if final := got[len(got)-1]; final.Partial || final.Content.Parts[0].Text != "Hello" {
+		t.Errorf("final response = %+v, want text %q", final, "Hello")
+	}
+}
+
+func TestGenerateStream_IdleTimeout(t *testing.T) {
+	m := newTestModel(t, &Config{StreamIdleTimeout: 50 * time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, textStreamEvents("Hello", "end_turn")[:2]...)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	var err error
+	start := time.Now()
+	for _, e := range m.GenerateContent(t.Context(), req, true) {
+		if e != nil {
+			err = e
+		}
+	}
+	if !errors.Is(err, ErrStreamIdleTimeout) {
+		t.Errorf("GenerateContent() error = %v, want ErrStreamIdleTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stream aborted after %v, want shortly after the idle timeout", elapsed)
+	}
+}
+
+func TestGenerateStream_IdleTimeoutNotArmedBeforeResponse(t *testing.T) {
+	var calls atomic.Int32
+	cfg := &Config{StreamIdleTimeout: 100 * time.Millisecond, RetryPolicy: &RetryPolicy{MaxAttempts: 2}}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// The backoff before the retry outlasts the idle timeout
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(statusOverloaded)
+			writeJSON(w, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+			return
+		}
+		// So does the wait for the response
+		time.Sleep(300 * time.Millisecond)
+		writeSSE(w, textStreamEvents("Hello", "end_turn")...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	got := collect(t, m, req, true)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+	if final := got[len(got)-1]; final.Partial || final.Content.Parts[0].Text != "Hello" {
+		t.Errorf("final response = %+v, want text %q", final, "Hello")
+	}
+}
+
+func TestGenerateStream_IdleTimeoutSlowConsumer(t *testing.T) {
+	m := newTestModel(t, &Config{StreamIdleTimeout: 50 * time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, deltaStreamEvents("He", "llo")...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	var final *model.LLMResponse
+	for resp, err := range m.GenerateContent(t.Context(), req, true) {
+		if err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		// Handling each response outlasts the idle timeout
+		time.Sleep(100 * time.Millisecond)
+		final = resp
+	}
+	if final.Partial || final.Content.Parts[0].Text != "Hello" {
+		t.Errorf("final response = %+v, want text %q", final, "Hello")
+	}
+}
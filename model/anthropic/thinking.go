@@ -0,0 +1,72 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// interleavedThinkingBeta is the beta flag that lets Claude think between tool calls.
+const interleavedThinkingBeta = anthropic.AnthropicBetaInterleavedThinking2025_05_14
+
+// defaultThinkingBudget is the thinking budget, in tokens, used when thinking is
+// enabled and the request does not set a ThinkingBudget.
+const defaultThinkingBudget = 2048
+
+// minThinkingBudget is the smallest thinking budget the API accepts.
+const minThinkingBudget = 1024
+
+// applyThinking enables extended thinking when the configuration or the
+// request's ThinkingConfig asks for it.
+//
+// The budget is taken from the request's ThinkingConfig, if set. A budget of
+// 0 turns thinking off, as in genai, even with IncludeThoughts, unless
+// Config.InterleavedThinking is set; a negative budget (automatic in genai)
+// uses the default. The API requires max_tokens to
+// exceed the budget, so when it does not, the budget is added to it and the
+// answer keeps the requested number of tokens. Thinking is incompatible with
+// modified temperature and top_k, so those are cleared.
+func (m *anthropicModel) applyThinking(req *model.LLMRequest, params *anthropic.MessageNewParams) error {
+	var tc *genai.ThinkingConfig
+	if req.Config != nil {
+		tc = req.Config.ThinkingConfig
+	}
+	disabled := tc != nil && tc.ThinkingBudget != nil && *tc.ThinkingBudget == 0
+	requested := tc != nil && !disabled && (tc.IncludeThoughts || tc.ThinkingBudget != nil)
+	if !m.cfg.InterleavedThinking && !requested {
+		return nil
+	}
+
+	budget := int64(defaultThinkingBudget)
+	if tc != nil && tc.ThinkingBudget != nil && *tc.ThinkingBudget > 0 {
+		budget = int64(*tc.ThinkingBudget)
+	}
+	if budget < minThinkingBudget {
+		return fmt.Errorf("ThinkingBudget %d is below the minimum of %d tokens accepted by Anthropic", budget, minThinkingBudget)
+	}
+	if params.MaxTokens <= budget {
+		params.MaxTokens += budget
+	}
+	params.Thinking = anthropic.ThinkingConfigParamOfEnabled(budget)
+	params.Temperature = param.Opt[float64]{}
+	params.TopK = param.Opt[int64]{}
+	return nil
+}
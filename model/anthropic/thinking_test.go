@@ -0,0 +1,303 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// interleavedThinkingEvents streams thinking, a tool call, more thinking, then text.
+var interleavedThinkingEvents = []string{
+	`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+	`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"I should look it up."}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"c2lnMQ=="}}`,
+	`{"type":"content_block_stop","index":0}`,
+	`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}}`,
+	`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"q\":\"x\"}"}}`,
+	`{"type":"content_block_stop","index":1}`,
+	`{"type":"content_block_start","index":2,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+	`{"type":"content_block_delta","index":2,"delta":{"type":"thinking_delta","thinking":"Now answer."}}`,
+	`{"type":"content_block_delta","index":2,"delta":{"type":"signature_delta","signature":"c2lnMg=="}}`,
+	`{"type":"content_block_stop","index":2}`,
+	`{"type":"content_block_start","index":3,"content_block":{"type":"text","text":""}}`,
+	`{"type":"content_block_delta","index":3,"delta":{"type":"text_delta","text":"Done."}}`,
+	`{"type":"content_block_stop","index":3}`,
+	`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":15}}`,
+	`{"type":"message_stop"}`,
+}
+
+func TestInterleavedThinking(t *testing.T) {
+	var gotBody, gotBeta string
+	cfg := &Config{InterleavedThinking: true, DefaultTemperature: genai.Ptr(0.2)}
+	m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotBeta = r.Header.Get("anthropic-beta")
+		writeSSE(w, interleavedThinkingEvents...)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")},
+		Config:   &genai.GenerateContentConfig{ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](4000)}},
+	}
+	got := collect(t, m, req, true)
+
+	if gotBeta != interleavedThinkingBeta {
+		t.Errorf("anthropic-beta = %q, want %q", gotBeta, interleavedThinkingBeta)
+	}
+	if !strings.Contains(gotBody, `"thinking":{"budget_tokens":4000,"type":"enabled"}`) {
+		t.Errorf("request body = %s, want thinking enabled with budget", gotBody)
+	}
+	if strings.Contains(gotBody, `"temperature"`) {
+		t.Errorf("request body = %s, want no temperature with thinking", gotBody)
+	}
+
+	var thoughts []string
+	for _, resp := range got {
+		if resp.Partial && resp.Content != nil && resp.Content.Parts[0].Thought && resp.Content.Parts[0].Text != "" {
+			thoughts = append(thoughts, resp.Content.Parts[0].Text)
+		}
+	}
+	if diff := cmp.Diff([]string{"I should look it up.", "Now answer."}, thoughts); diff != "" {
+		t.Errorf("streamed thoughts mismatch (-want +got):\n%s", diff)
+	}
+
+	final := got[len(got)-1]
+	if n := len(final.Content.Parts); n != 4 {
+		t.Fatalf("final response has %d parts, want 4", n)
+	}
+	if call := final.Content.Parts[1].FunctionCall; call == nil || call.Name != "lookup" {
+		t.Errorf("final part 1 = %+v, want lookup call", final.Content.Parts[1])
+	}
+}
+
+func TestThinkingBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          Config
+		genCfg       *genai.GenerateContentConfig
+		wantThinking string
+		wantMax      string
+		wantErr      string
+	}{
+		{
+			name:         "max_tokens_below_default_budget",
+			cfg:          Config{InterleavedThinking: true},
+			genCfg:       &genai.GenerateContentConfig{MaxOutputTokens: 1024},
+			wantThinking: `"thinking":{"budget_tokens":2048,"type":"enabled"}`,
+			wantMax:      `"max_tokens":3072`,
+		},
+		{
+			name:         "max_tokens_above_budget",
+			cfg:          Config{InterleavedThinking: true},
+			genCfg:       &genai.GenerateContentConfig{MaxOutputTokens: 8000},
+			wantThinking: `"thinking":{"budget_tokens":2048,"type":"enabled"}`,
+			wantMax:      `"max_tokens":8000`,
+		},
+		{
+			name:    "budget_below_minimum",
+			cfg:     Config{InterleavedThinking: true},
+			genCfg:  &genai.GenerateContentConfig{ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](512)}},
+			wantErr: "ThinkingBudget 512 is below the minimum of 1024 tokens",
+		},
+		{
+			name:         "thinking_config_without_interleaved",
+			genCfg:       &genai.GenerateContentConfig{MaxOutputTokens: 4096, ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](1500)}},
+			wantThinking: `"thinking":{"budget_tokens":1500,"type":"enabled"}`,
+			wantMax:      `"max_tokens":4096`,
+		},
+		{
+			name:         "include_thoughts",
+			genCfg:       &genai.GenerateContentConfig{MaxOutputTokens: 4096, ThinkingConfig: &genai.ThinkingConfig{IncludeThoughts: true}},
+			wantThinking: `"thinking":{"budget_tokens":2048,"type":"enabled"}`,
+			wantMax:      `"max_tokens":4096`,
+		},
+		{
+			name:   "zero_budget",
+			genCfg: &genai.GenerateContentConfig{MaxOutputTokens: 4096, ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](0)}},
+		},
+		{
+			name:   "zero_budget_with_include_thoughts",
+			genCfg: &genai.GenerateContentConfig{MaxOutputTokens: 4096, ThinkingConfig: &genai.ThinkingConfig{IncludeThoughts: true, ThinkingBudget: genai.Ptr[int32](0)}},
+		},
+		{
+			name:         "zero_budget_with_interleaved",
+			cfg:          Config{InterleavedThinking: true},
+			genCfg:       &genai.GenerateContentConfig{MaxOutputTokens: 4096, ThinkingConfig: &genai.ThinkingConfig{IncludeThoughts: true, ThinkingBudget: genai.Ptr[int32](0)}},
+			wantThinking: `"thinking":{"budget_tokens":2048,"type":"enabled"}`,
+			wantMax:      `"max_tokens":4096`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody string
+			m := newTestModel(t, &tt.cfg, func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				gotBody = string(b)
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}, Config: tt.genCfg}
+			var err error
+			for _, err = range m.GenerateContent(t.Context(), req, false) {
+				if err != nil {
+					break
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("GenerateContent() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateContent() error = %v", err)
+			}
+			if tt.wantThinking == "" {
+				if strings.Contains(gotBody, `"thinking"`) {
+					t.Errorf("request body = %s, want no thinking", gotBody)
+				}
+				return
+			}
+			if !strings.Contains(gotBody, tt.wantThinking) || !strings.Contains(gotBody, tt.wantMax) {
+				t.Errorf("request body = %s, want %s and %s", gotBody, tt.wantThinking, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestGenerateStream_ThoughtSignature(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want []*genai.Part
+	}{
+		{
+			name: "unbuffered",
+			want: []*genai.Part{
+				{Text: "I should look it up.", Thought: true},
+				{Thought: true, ThoughtSignature: []byte("sig1")},
+				{Text: "Now answer.", Thought: true},
+				{Thought: true, ThoughtSignature: []byte("sig2")},
+			},
+		},
+		{
+			name: "buffered",
+			cfg:  &Config{StreamBufferChars: 1000},
+			want: []*genai.Part{
+				{Text: "I should look it up.", Thought: true, ThoughtSignature: []byte("sig1")},
+				{Text: "Now answer.", Thought: true, ThoughtSignature: []byte("sig2")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, tt.cfg, func(w http.ResponseWriter, r *http.Request) {
+				writeSSE(w, interleavedThinkingEvents...)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, true)
+
+			var thoughts []*genai.Part
+			for _, resp := range got {
+				if resp.Partial && resp.Content != nil && resp.Content.Parts[0].Thought {
+					thoughts = append(thoughts, resp.Content.Parts[0])
+				}
+			}
+			if diff := cmp.Diff(tt.want, thoughts); diff != "" {
+				t.Errorf("streamed thoughts mismatch (-want +got):\n%s", diff)
+			}
+
+			final := got[len(got)-1]
+			for i, want := range []string{"sig1", "sig2"} {
+				part := final.Content.Parts[2*i]
+				if string(part.ThoughtSignature) != want {
+					t.Errorf("final thought %d signature = %q, want %q", i, part.ThoughtSignature, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateStream_ThoughtSignatureHistory(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Two plus "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"two is four."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"c2lnMQ=="}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"4"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":15}}`,
+		`{"type":"message_stop"}`,
+	}
+	m := newTestModel(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, events...)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("What is 2+2?", "user")}}
+	got := collect(t, m, req, true)
+
+	// Rebuild the model turn from the partials alone, as a caller that does
+	// not keep the final response would
+	thought := &genai.Part{Thought: true}
+	answer := &genai.Part{}
+	for _, resp := range got {
+		if !resp.Partial || resp.Content == nil {
+			continue
+		}
+		for _, part := range resp.Content.Parts {
+			if !part.Thought {
+				answer.Text += part.Text
+				continue
+			}
+			thought.Text += part.Text
+			if len(part.ThoughtSignature) > 0 {
+				thought.ThoughtSignature = part.ThoughtSignature
+			}
+		}
+	}
+
+	history := &model.LLMRequest{Contents: []*genai.Content{
+		req.Contents[0],
+		{Role: "model", Parts: []*genai.Part{thought, answer}},
+		genai.NewContentFromText("And 3+3?", "user"),
+	}}
+	params, err := m.convertRequest(t.Context(), history)
+	if err != nil {
+		t.Fatalf("convertRequest() error = %v", err)
+	}
+	block := params.Messages[1].Content[0].OfThinking
+	if block == nil {
+		t.Fatalf("first block of the model turn = %+v, want thinking", params.Messages[1].Content[0])
+	}
+	if block.Thinking != "Two plus two is four." || block.Signature != "c2lnMQ==" {
+		t.Errorf("thinking block = %q signed %q, want %q signed %q", block.Thinking, block.Signature, "Two plus two is four.", "c2lnMQ==")
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"google.golang.org/genai"
+)
+
+// Names of Claude's built-in client tools. Calls to these tools are reported as
+// genai.FunctionCall parts with the same name.
+const (
+	// BashToolName is the name of the bash tool (bash_20250124).
+	BashToolName = "bash"
+	// TextEditorToolName is the name of the text editor tool (text_editor_20250124).
+	TextEditorToolName = "str_replace_editor"
+)
+
+// replaceTool returns tools with any custom tool with the given name removed
+// and the built-in tool appended.
+//
+// Built-in tools are executed by the caller, so agents register a function tool
+// with the same name. The function declaration is dropped in favor of the
+// built-in definition, which Claude is trained to use.
+func replaceTool(tools []anthropic.ToolUnionParam, name string, builtin anthropic.ToolUnionParam) []anthropic.ToolUnionParam {
+	result := make([]anthropic.ToolUnionParam, 0, len(tools)+1)
+	for _, t := range tools {
+		if t.OfTool != nil && t.OfTool.Name == name {
+			continue
+		}
+		result = append(result, t)
+	}
+	return append(result, builtin)
+}
+
+// applyBuiltinTools adds the built-in tools enabled in cfg to tools.
+func applyBuiltinTools(cfg *Config, tools []anthropic.ToolUnionParam) []anthropic.ToolUnionParam {
+	if cfg.ComputerUse != nil {
+		tools = replaceTool(tools, ComputerUseToolName, cfg.ComputerUse.tool())
+	}
+	if cfg.BashTool {
+		tools = replaceTool(tools, BashToolName, anthropic.ToolUnionParam{OfBashTool20250124: &anthropic.ToolBash20250124Param{}})
+	}
+	if cfg.TextEditorTool {
+		tools = replaceTool(tools, TextEditorToolName, anthropic.ToolUnionParam{OfTextEditor20250124: &anthropic.ToolTextEditor20250124Param{}})
+	}
+	if cfg.CodeExecutionTool {
+		tools = replaceTool(tools, CodeExecutionToolName, codeExecutionTool())
+	}
+	return tools
+}
+
+// BashCommand is a command requested through the bash tool.
+type BashCommand struct {
+	// Command is the shell command to run.
+	Command string `json:"command,omitempty"`
+	// Restart requests a restart of the shell session.
+	Restart bool `json:"restart,omitempty"`
+}
+
+// ParseBashCommand decodes the arguments of a bash tool function call.
+func ParseBashCommand(call *genai.FunctionCall) (*BashCommand, error) {
+	var cmd BashCommand
+	if err := decodeToolCall(call, BashToolName, &cmd); err != nil {
+		return nil, err
+	}
+	if cmd.Command == "" && !cmd.Restart {
+		return nil, fmt.Errorf("bash call has neither a command nor restart")
+	}
+	return &cmd, nil
+}
+
+// Text editor commands reported in [TextEditorCommand.Command].
+const (
+	TextEditorView       = "view"
+	TextEditorCreate     = "create"
+	TextEditorStrReplace = "str_replace"
+	TextEditorInsert     = "insert"
+	TextEditorUndoEdit   = "undo_edit"
+)
+
+// TextEditorCommand is a command requested through the text editor tool.
+type TextEditorCommand struct {
+	// Command is the editor command, such as TextEditorView.
+	Command string `json:"command"`
+	// Path is the file or directory the command applies to.
+	Path string `json:"path"`
+	// FileText is the content of the file to create.
+	FileText string `json:"file_text,omitempty"`
+	// OldStr is the text to replace for str_replace.
+	OldStr string `json:"old_str,omitempty"`
+	// NewStr is the replacement text for str_replace, or the text to insert.
+	NewStr string `json:"new_str,omitempty"`
+	// InsertLine is the line after which to insert NewStr.
+	InsertLine int `json:"insert_line,omitempty"`
+	// ViewRange is the optional [start, end] line range to view.
+	ViewRange []int `json:"view_range,omitempty"`
+}
+
+// ParseTextEditorCommand decodes the arguments of a text editor tool function call.
+func ParseTextEditorCommand(call *genai.FunctionCall) (*TextEditorCommand, error) {
+	var cmd TextEditorCommand
+	if err := decodeToolCall(call, TextEditorToolName, &cmd); err != nil {
+		return nil, err
+	}
+	if cmd.Command == "" || cmd.Path == "" {
+		return nil, fmt.Errorf("text editor call requires command and path")
+	}
+	return &cmd, nil
+}
+
+// decodeToolCall decodes the arguments of a call to the named tool into v.
+func decodeToolCall(call *genai.FunctionCall, name string, v any) error {
+	if call == nil || call.Name != name {
+		return fmt.Errorf("not a %s tool call", name)
+	}
+	b, err := json.Marshal(call.Args)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s arguments: %w", name, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to decode %s arguments: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestBuiltinCodingTools(t *testing.T) {
+	var gotBody, gotBeta string
+	m := newTestModel(t, &Config{BashTool: true, TextEditorTool: true}, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotBeta = r.Header.Get("anthropic-beta")
+		writeJSON(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[`+
+			`{"type":"tool_use","id":"toolu_1","name":"bash","input":{"command":"ls -la"}},`+
+			`{"type":"tool_use","id":"toolu_2","name":"str_replace_editor","input":{"command":"str_replace","path":"/tmp/a.go","old_str":"foo","new_str":"bar"}}`+
+			`],"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":25,"output_tokens":15}}`)
+	})
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Rename foo", "user")},
+		Config: &genai.GenerateContentConfig{
+			Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+				{Name: BashToolName, Description: "bash executor"},
+				{Name: TextEditorToolName, Description: "editor executor"},
+			}}},
+		},
+	}
+	got := collect(t, m, req, false)
+
+	if gotBeta != computerUseBeta {
+		t.Errorf("anthropic-beta = %q, want %q", gotBeta, computerUseBeta)
+	}
+	want := `"tools":[{"name":"bash","type":"bash_20250124"},{"name":"str_replace_editor","type":"text_editor_20250124"}]`
+	if !strings.Contains(gotBody, want) {
+		t.Errorf("request body = %s, want %s", gotBody, want)
+	}
+
+	parts := got[0].Content.Parts
+	bash, err := ParseBashCommand(parts[0].FunctionCall)
+	if err != nil {
+		t.Fatalf("ParseBashCommand() error = %v", err)
+	}
+	if diff := cmp.Diff(&BashCommand{Command: "ls -la"}, bash); diff != "" {
+		t.Errorf("ParseBashCommand() mismatch (-want +got):\n%s", diff)
+	}
+
+	edit, err := ParseTextEditorCommand(parts[1].FunctionCall)
+	if err != nil {
+		t.Fatalf("ParseTextEditorCommand() error = %v", err)
+	}
+	wantEdit := &TextEditorCommand{Command: TextEditorStrReplace, Path: "/tmp/a.go", OldStr: "foo", NewStr: "bar"}
+	if diff := cmp.Diff(wantEdit, edit); diff != "" {
+		t.Errorf("ParseTextEditorCommand() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseTextEditorCommand_MissingPath(t *testing.T) {
+	call := &genai.FunctionCall{Name: TextEditorToolName, Args: map[string]any{"command": "view"}}
+	if _, err := ParseTextEditorCommand(call); err == nil {
+		t.Error("ParseTextEditorCommand() error = nil, want error")
+	}
+}
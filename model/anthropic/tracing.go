@@ -0,0 +1,133 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"google.golang.org/adk/model"
+)
+
+const instrumentationName = "google.golang.org/adk/model/anthropic"
+
+// Span attributes, following the OpenTelemetry semantic conventions for
+// generative AI client spans.
+const (
+	genAiOperationName          = "gen_ai.operation.name"
+	genAiProviderName           = "gen_ai.provider.name"
+	genAiRequestModel           = "gen_ai.request.model"
+	genAiUsageInputTokens       = "gen_ai.usage.input_tokens"
+	genAiUsageOutputTokens      = "gen_ai.usage.output_tokens"
+	genAiResponseFinishReasons  = "gen_ai.response.finish_reasons"
+	genAiServerTimeToFirstToken = "gen_ai.server.time_to_first_token"
+	errorType                   = "error.type"
+
+	chatOperation = "chat"
+)
+
+// tracer returns the tracer to trace model calls with: Config.Tracer if set,
+// otherwise a tracer from the provider of the span in ctx, if any.
+func (m *anthropicModel) tracer(ctx context.Context) trace.Tracer {
+	if m.cfg.Tracer != nil {
+		return m.cfg.Tracer
+	}
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		return span.TracerProvider().Tracer(instrumentationName)
+	}
+	return nil
+}
+
+// providerName returns the gen_ai.provider.name of the model's backend.
+func (m *anthropicModel) providerName() string {
+	if m.variant == VariantVertexAI {
+		return "gcp.vertex_ai"
+	}
+	return "anthropic"
+}
+
+// traced wraps a model call in a client span when tracing is configured.
+// The span records token usage, finish reason and errors, and, for streaming
+// calls, the time to the first streamed content.
+func (m *anthropicModel) traced(ctx context.Context, generate func(context.Context) iter.Seq2[*model.LLMResponse, error]) iter.Seq2[*model.LLMResponse, error] {
+	tracer := m.tracer(ctx)
+	if tracer == nil {
+		return generate(ctx)
+	}
+
+	return func(yield func(*model.LLMResponse, error) bool) {
+		ctx, span := tracer.Start(ctx, chatOperation+" "+string(m.name),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String(genAiOperationName, chatOperation),
+				attribute.String(genAiProviderName, m.providerName()),
+				attribute.String(genAiRequestModel, string(m.name)),
+			))
+		defer span.End()
+
+		start := time.Now()
+		firstToken := true
+		for resp, err := range generate(ctx) {
+			switch {
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				span.SetAttributes(attribute.String(errorType, errorTypeOf(err)))
+			case resp.Partial:
+				if firstToken && resp.Content != nil {
+					firstToken = false
+					span.SetAttributes(attribute.Float64(genAiServerTimeToFirstToken, time.Since(start).Seconds()))
+				}
+			default:
+				setResponseAttributes(span, resp)
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
+
+// setResponseAttributes records the usage and finish reason of a final response.
+func setResponseAttributes(span trace.Span, resp *model.LLMResponse) {
+	if resp.UsageMetadata != nil {
+		span.SetAttributes(
+			attribute.Int(genAiUsageInputTokens, int(resp.UsageMetadata.PromptTokenCount)),
+			attribute.Int(genAiUsageOutputTokens, int(resp.UsageMetadata.CandidatesTokenCount+resp.UsageMetadata.ThoughtsTokenCount)),
+		)
+	}
+	if resp.FinishReason != "" {
+		span.SetAttributes(attribute.StringSlice(genAiResponseFinishReasons, []string{strings.ToLower(string(resp.FinishReason))}))
+	}
+}
+
+// errorTypeOf returns a low-cardinality error.type for err: the HTTP status
+// code for API errors, otherwise "_OTHER".
+func errorTypeOf(err error) string {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		return strconv.Itoa(apiErr.StatusCode)
+	}
+	return "_OTHER"
+}
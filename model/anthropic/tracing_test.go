@@ -0,0 +1,107 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+func TestTracing(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			m := newTestModel(t, &Config{Tracer: tp.Tracer("test")}, func(w http.ResponseWriter, r *http.Request) {
+				if stream {
+					writeSSE(w, textStreamEvents("ok", "end_turn")...)
+					return
+				}
+				writeJSON(w, okMessage)
+			})
+
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			collect(t, m, req, stream)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if got, want := span.Name(), "chat claude-sonnet-4-20250514"; got != want {
+				t.Errorf("span name = %q, want %q", got, want)
+			}
+
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range span.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			if got := attrs[genAiProviderName].AsString(); got != "anthropic" {
+				t.Errorf("%s = %q, want %q", genAiProviderName, got, "anthropic")
+			}
+			if got := attrs[genAiRequestModel].AsString(); got != "claude-sonnet-4-20250514" {
+				t.Errorf("%s = %q, want model name", genAiRequestModel, got)
+			}
+			if got := attrs[genAiUsageInputTokens].AsInt64(); got != 25 {
+				t.Errorf("%s = %d, want 25", genAiUsageInputTokens, got)
+			}
+			if got := attrs[genAiUsageOutputTokens].AsInt64(); got != 15 {
+				t.Errorf("%s = %d, want 15", genAiUsageOutputTokens, got)
+			}
+			if got := attrs[genAiResponseFinishReasons].AsStringSlice(); len(got) != 1 || got[0] != "stop" {
+				t.Errorf("%s = %v, want [stop]", genAiResponseFinishReasons, got)
+			}
+			if _, ok := attrs[genAiServerTimeToFirstToken]; ok != stream {
+				t.Errorf("%s recorded = %v, want %v", genAiServerTimeToFirstToken, ok, stream)
+			}
+		})
+	}
+}
+
+func TestTracing_Error(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	m := newTestModel(t, &Config{Tracer: tp.Tracer("test")}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)
+	})
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+	for _, err := range m.GenerateContent(t.Context(), req, false) {
+		if err == nil {
+			t.Fatal("GenerateContent() error = nil, want error")
+		}
+	}
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want %v", span.Status().Code, codes.Error)
+	}
+	for _, kv := range span.Attributes() {
+		if kv.Key == errorType && kv.Value.AsString() != "400" {
+			t.Errorf("%s = %q, want %q", errorType, kv.Value.AsString(), "400")
+		}
+	}
+}
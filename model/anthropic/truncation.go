@@ -0,0 +1,70 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+)
+
+// TruncationStrategy shortens a conversation that does not fit in the context
+// window of the model, for [Config.AutoTruncate]. It is called repeatedly
+// with the remaining contents until the request fits, and must return fewer
+// contents than it was given, or the same contents to give up. It must not
+// modify contents, and should keep function calls together with their
+// responses, since the API rejects a tool result without its tool use.
+type TruncationStrategy func(contents []*genai.Content) []*genai.Content
+
+// DropOldestTurns is the default [TruncationStrategy]. It drops the oldest
+// turn of the conversation: the first user message and everything up to the
+// next user message that is not made of function responses, so that function
+// calls and their responses are dropped together and the conversation still
+// starts with a user message. System contents and the last turn are kept.
+func DropOldestTurns(contents []*genai.Content) []*genai.Content {
+	start := slices.IndexFunc(contents, func(c *genai.Content) bool { return !converters.IsSystemContent(c) })
+	if start < 0 {
+		return contents
+	}
+	end := start + 1
+	for end < len(contents) && !isTurnStart(contents[end]) {
+		end++
+	}
+	if end == len(contents) {
+		return contents
+	}
+
+	truncated := slices.Clone(contents[:start])
+	for _, c := range contents[start:end] {
+		if converters.IsSystemContent(c) {
+			truncated = append(truncated, c)
+		}
+	}
+	return append(truncated, contents[end:]...)
+}
+
+// isTurnStart reports whether c is a user message that can start a
+// conversation: one that is not made of function responses.
+func isTurnStart(c *genai.Content) bool {
+	if c == nil || (!strings.EqualFold(c.Role, "user") && c.Role != "") {
+		return false
+	}
+	return !slices.ContainsFunc(c.Parts, func(p *genai.Part) bool {
+		return p != nil && p.FunctionResponse != nil
+	})
+}
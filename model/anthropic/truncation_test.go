@@ -0,0 +1,174 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/internal/anthropicllm/converters"
+	"google.golang.org/adk/model"
+)
+
+// toolConversation returns a conversation of three questions, the first two
+// of which are answered after a function call.
+func toolConversation() []*genai.Content {
+	var contents []*genai.Content
+	for i := range 2 {
+		id := fmt.Sprintf("toolu_%d", i)
+		contents = append(contents,
+			genai.NewContentFromText(fmt.Sprintf("q%d", i), "user"),
+			&genai.Content{Role: "model", Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: id, Name: "lookup"}}}},
+			&genai.Content{Role: "user", Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{ID: id, Name: "lookup", Response: map[string]any{"result": i}}}}},
+			genai.NewContentFromText(fmt.Sprintf("a%d", i), "model"),
+		)
+	}
+	return append(contents, genai.NewContentFromText("q2", "user"))
+}
+
+func TestDropOldestTurns(t *testing.T) {
+	contents := toolConversation()
+	system := genai.NewContentFromText("Be brief.", "system")
+
+	tests := []struct {
+		name     string
+		contents []*genai.Content
+		want     []*genai.Content
+	}{
+		{name: "drops_first_turn", contents: contents, want: contents[4:]},
+		{name: "keeps_last_turn", contents: contents[8:], want: contents[8:]},
+		{name: "keeps_system", contents: append([]*genai.Content{system}, contents...), want: append([]*genai.Content{system}, contents[4:]...)},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, DropOldestTurns(tt.contents)); diff != "" {
+				t.Errorf("DropOldestTurns() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// messageTokens returns the number of tokens of messages counted by the
+// count_tokens handler of TestGenerate_AutoTruncate: one per byte of their JSON
+// encoding.
+func messageTokens(messages []json.RawMessage) int {
+	n := 0
+	for _, msg := range messages {
+		n += len(msg)
+	}
+	return n
+}
+
+func TestGenerate_AutoTruncate(t *testing.T) {
+	// The tokens of the conversation, of its last two turns and of its last
+	// turn. The windows fall between them, since dropped tokens are estimated.
+	tokens := func(contents []*genai.Content) int {
+		messages, err := converters.ContentsToMessages(contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw []json.RawMessage
+		for _, msg := range messages {
+			data, _ := json.Marshal(msg)
+			raw = append(raw, data)
+		}
+		return messageTokens(raw)
+	}
+	contents := toolConversation()
+	all, lastTwo, last := tokens(contents), tokens(contents[4:]), tokens(contents[8:])
+
+	tests := []struct {
+		name         string
+		window       int
+		wantMessages int
+		wantErr      bool
+	}{
+		{name: "fits", window: all + 1024, wantMessages: 9},
+		{name: "drops_one_turn", window: (all+lastTwo)/2 + 1024, wantMessages: 5},
+		{name: "drops_two_turns", window: (lastTwo+last)/2 + 1024, wantMessages: 1},
+		{name: "too_large", window: 500, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []json.RawMessage
+			var counts int
+			cfg := &Config{AutoTruncate: true, ContextWindow: tt.window, DefaultMaxTokens: 1024}
+			m := newTestModel(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Messages []json.RawMessage `json:"messages"`
+				}
+				data, _ := io.ReadAll(r.Body)
+				if err := json.Unmarshal(data, &body); err != nil {
+					t.Errorf("request body: %v", err)
+				}
+				if r.URL.Path == "/v1/messages/count_tokens" {
+					counts++
+					writeJSON(w, fmt.Sprintf(`{"input_tokens":%d}`, messageTokens(body.Messages)))
+					return
+				}
+				sent = body.Messages
+				writeJSON(w, okMessage)
+			})
+
+			contents := toolConversation()
+			req := &model.LLMRequest{Contents: contents}
+			var err error
+			for _, e := range m.GenerateContent(t.Context(), req, false) {
+				err = e
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrContextLimitExceeded) {
+					t.Errorf("GenerateContent() error = %v, want ErrContextLimitExceeded", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateContent() error = %v", err)
+			}
+			if len(sent) != tt.wantMessages {
+				t.Fatalf("sent %d messages, want %d", len(sent), tt.wantMessages)
+			}
+			if counts > maxTruncationCounts {
+				t.Errorf("counted tokens %d times, want at most %d", counts, maxTruncationCounts)
+			}
+			var first struct {
+				Role    string `json:"role"`
+				Content []struct {
+					Type string `json:"type"`
+				} `json:"content"`
+			}
+			if err := json.Unmarshal(sent[0], &first); err != nil {
+				t.Fatal(err)
+			}
+			if first.Role != "user" || first.Content[0].Type != "text" {
+				t.Errorf("first message = %s, want a user text message", sent[0])
+			}
+			if diff := cmp.Diff(toolConversation(), contents); diff != "" {
+				t.Errorf("request contents modified (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"google.golang.org/adk/model"
+)
+
+// ErrorCodeAuthentication is the [model.LLMResponse] ErrorCode reported when
+// the Google credentials used for Vertex AI cannot produce an access token,
+// for example because they expired or were revoked.
+const ErrorCodeAuthentication = "AUTHENTICATION"
+
+// tokenError marks an error returned while fetching a Google access token.
+type tokenError struct {
+	err error
+}
+
+func (e *tokenError) Error() string { return e.err.Error() }
+func (e *tokenError) Unwrap() error { return e.err }
+
+// authTokenSource wraps a token source so that its errors can be told apart
+// from other transport errors.
+type authTokenSource struct {
+	src oauth2.TokenSource
+}
+
+// Token implements oauth2.TokenSource.
+func (s authTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, &tokenError{err}
+	}
+	return tok, nil
+}
+
+// authErrorResponse returns the response reported for err if it is a failure
+// to obtain Google credentials, or nil otherwise.
+func authErrorResponse(err error) *model.LLMResponse {
+	if te := (*tokenError)(nil); errors.As(err, &te) {
+		return &model.LLMResponse{
+			ErrorCode: ErrorCodeAuthentication,
+			ErrorMessage: "failed to obtain Google Cloud credentials for Vertex AI: " + te.Error() +
+				"; refresh them with `gcloud auth application-default login` or check GOOGLE_APPLICATION_CREDENTIALS",
+		}
+	}
+	return nil
+}
+
+// withVertexLocation returns cfg with VertexProjectID and VertexRegion filled
+// in from GOOGLE_CLOUD_PROJECT and GOOGLE_CLOUD_REGION, or else from
+// Application Default Credentials and the metadata server, as on GKE with
+// workload identity where the environment variables are often not set. Values
+// that cannot be resolved are left empty.
+func withVertexLocation(ctx context.Context, cfg *Config) *Config {
+	resolved := *cfg
+	resolved.VertexProjectID = cmp.Or(cfg.VertexProjectID, os.Getenv("GOOGLE_CLOUD_PROJECT"))
+	resolved.VertexRegion = cmp.Or(cfg.VertexRegion, os.Getenv("GOOGLE_CLOUD_REGION"))
+	if resolved.VertexProjectID == "" {
+		// The credentials hold the project of a service account key, or of
+		// the metadata server on Google Cloud
+		if creds, err := google.FindDefaultCredentials(ctx); err == nil {
+			resolved.VertexProjectID = creds.ProjectID
+		}
+	}
+	if resolved.VertexRegion == "" {
+		resolved.VertexRegion = metadataRegion(ctx)
+	}
+	return &resolved
+}
+
+// metadataRegion returns the region of the zone reported by the metadata
+// server, or an empty string when not running on Google Cloud.
+func metadataRegion(ctx context.Context) string {
+	client := metadata.NewClient(nil)
+	if !client.OnGCEWithContext(ctx) {
+		return ""
+	}
+	zone, err := client.ZoneWithContext(ctx)
+	if err != nil {
+		return ""
+	}
+	// Zones are named after their region, as in "us-east5-a"
+	i := strings.LastIndex(zone, "-")
+	if i < 0 {
+		return ""
+	}
+	return zone[:i]
+}
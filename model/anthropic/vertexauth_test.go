@@ -0,0 +1,176 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package anthropic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/vertex"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/genai"
+
+	"google.golang.org/adk/model"
+)
+
+// failingTokenSource fails like credentials whose refresh token was revoked.
+type failingTokenSource struct{}
+
+func (failingTokenSource) Token() (*oauth2.Token, error) {
+	return nil, errors.New("oauth2: \"invalid_grant\" \"reauth related error (invalid_rapt)\"")
+}
+
+func TestGenerate_VertexAuthFailure(t *testing.T) {
+	creds := &google.Credentials{TokenSource: authTokenSource{failingTokenSource{}}}
+	client := anthropic.NewClient(option.WithMaxRetries(0), vertex.WithCredentials(t.Context(), "us-east5", "my-project", creds))
+	m := &anthropicModel{
+		client:           &client,
+		name:             "claude-sonnet-4@20250514",
+		variant:          VariantVertexAI,
+		defaultMaxTokens: defaultMaxTokens,
+	}
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("Hi", "user")}}
+			got := collect(t, m, req, stream)
+
+			if len(got) != 1 {
+				t.Fatalf("got %d responses, want 1", len(got))
+			}
+			if got[0].ErrorCode != ErrorCodeAuthentication {
+				t.Errorf("ErrorCode = %q, want %q", got[0].ErrorCode, ErrorCodeAuthentication)
+			}
+			if !strings.Contains(got[0].ErrorMessage, "invalid_grant") || !strings.Contains(got[0].ErrorMessage, "gcloud auth application-default login") {
+				t.Errorf("ErrorMessage = %q, want the cause and how to fix it", got[0].ErrorMessage)
+			}
+		})
+	}
+}
+
+// fakeMetadataServer serves the zone of a Google Cloud instance in zone, or
+// no metadata if zone is empty, and points the metadata client to it.
+func fakeMetadataServer(t *testing.T, zone string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if zone == "" || r.URL.Path != "/computeMetadata/v1/instance/zone" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "projects/123456/zones/%s", zone)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+}
+
+// writeServiceAccountKey writes a service account key file of project, without
+// a usable private key, and returns its path.
+func writeServiceAccountKey(t *testing.T, project string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.json")
+	key := fmt.Sprintf(`{"type":"service_account","project_id":%q,"client_email":"agent@%s.iam.gserviceaccount.com","private_key":"","token_uri":"https://oauth2.googleapis.com/token"}`, project, project)
+	if err := os.WriteFile(path, []byte(key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWithVertexLocation(t *testing.T) {
+	keyFile := writeServiceAccountKey(t, "adc-project")
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		env         map[string]string
+		zone        string
+		wantProject string
+		wantRegion  string
+	}{
+		{
+			name:        "config",
+			cfg:         Config{VertexProjectID: "my-project", VertexRegion: "europe-west1"},
+			env:         map[string]string{"GOOGLE_CLOUD_PROJECT": "env-project", "GOOGLE_CLOUD_REGION": "us-central1"},
+			zone:        "us-east5-a",
+			wantProject: "my-project",
+			wantRegion:  "europe-west1",
+		},
+		{
+			name:        "env",
+			env:         map[string]string{"GOOGLE_CLOUD_PROJECT": "env-project", "GOOGLE_CLOUD_REGION": "us-central1"},
+			zone:        "us-east5-a",
+			wantProject: "env-project",
+			wantRegion:  "us-central1",
+		},
+		{
+			name:        "adc",
+			zone:        "us-east5-a",
+			wantProject: "adc-project",
+			wantRegion:  "us-east5",
+		},
+		{
+			name:        "adc_without_metadata",
+			wantProject: "adc-project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_CLOUD_PROJECT", tt.env["GOOGLE_CLOUD_PROJECT"])
+			t.Setenv("GOOGLE_CLOUD_REGION", tt.env["GOOGLE_CLOUD_REGION"])
+			t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", keyFile)
+			fakeMetadataServer(t, tt.zone)
+
+			got := withVertexLocation(t.Context(), &tt.cfg)
+			if got.VertexProjectID != tt.wantProject || got.VertexRegion != tt.wantRegion {
+				t.Errorf("withVertexLocation() project, region = %q, %q, want %q, %q", got.VertexProjectID, got.VertexRegion, tt.wantProject, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestNewModel_VertexAI_LocationFromADC(t *testing.T) {
+	keyFile := writeServiceAccountKey(t, "adc-project")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv("GOOGLE_CLOUD_REGION", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", keyFile)
+	fakeMetadataServer(t, "us-east5-a")
+
+	if _, err := NewModel(t.Context(), "claude-sonnet-4@20250514", &Config{Variant: VariantVertexAI, DisableClientSharing: true}); err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+}
+
+func TestNewModel_VertexAI_NoCredentials(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+
+	_, err := NewModel(t.Context(), "claude-sonnet-4@20250514", &Config{
+		Variant:              VariantVertexAI,
+		VertexProjectID:      "my-project",
+		VertexRegion:         "us-east5",
+		DisableClientSharing: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to find default credentials") {
+		t.Errorf("NewModel() error = %v, want a credentials error", err)
+	}
+}